@@ -0,0 +1,145 @@
+// Package client is a Go client for the game server's JSON API and event stream
+//
+// Create or join a lobby to get a Session, then drive the game with its methods:
+//
+//	c := client.New("http://localhost:8080")
+//	s, lobby, err := c.CreateLobby(ctx, "Alice")
+//	...
+//	for ev, err := range s.Events(ctx) {
+//		...
+//	}
+//
+// The document types mirror those of the /api/v1 API (see its OpenAPI document at /api/v1/openapi.yaml)
+// and the event payloads those of docs/events.md. The package only needs the standard library.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiPrefix is the path prefix of the API version this client speaks
+const apiPrefix = "/api/v1"
+
+// Error is an error response of the server
+type Error struct {
+	StatusCode int
+	Code       string // one of the Code* constants
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// Client talks to one game server
+// It is safe for concurrent use; it holds no session state itself.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for every request (http.DefaultClient by default)
+// Event streams stay open for as long as they are read, so the client should not set a Timeout;
+// bound calls with their context instead.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CreateLobby creates a lobby hosted by a new player called name
+func (c *Client) CreateLobby(ctx context.Context, name string) (*Session, *Lobby, error) {
+	var resp sessionResponse
+	if err := c.do(ctx, http.MethodPost, "/lobbies", "", nameRequest{Name: name}, &resp); err != nil {
+		return nil, nil, err
+	}
+	return c.session(resp), &resp.Lobby, nil
+}
+
+// JoinLobby adds a new player called name to the lobby with the given code
+func (c *Client) JoinLobby(ctx context.Context, code, name string) (*Session, *Lobby, error) {
+	var resp sessionResponse
+	if err := c.do(ctx, http.MethodPost, "/lobbies/"+code+"/join", "", nameRequest{Name: name}, &resp); err != nil {
+		return nil, nil, err
+	}
+	return c.session(resp), &resp.Lobby, nil
+}
+
+// Resume returns the session of a player that joined earlier, e.g. from a saved token
+func (c *Client) Resume(code, playerID, token string) *Session {
+	return &Session{Code: code, PlayerID: playerID, Token: token, client: c}
+}
+
+// session wraps the session document returned by create and join
+func (c *Client) session(s sessionResponse) *Session {
+	return c.Resume(s.Lobby.Code, s.PlayerID, s.Token)
+}
+
+// do sends a JSON API request and decodes the response into out (unless it is nil)
+func (c *Client) do(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// responseError reads the error document of a failed response
+// Responses that are not API errors (e.g. from a proxy) keep their status and text
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var doc errorResponse
+	if json.Unmarshal(data, &doc) == nil && doc.Error.Code != "" {
+		return &Error{StatusCode: resp.StatusCode, Code: doc.Error.Code, Message: doc.Error.Message}
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+}
@@ -0,0 +1,355 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/client"
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/export"
+	"github.com/aaronzipp/you-are-officially-sus/internal/handlers"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/spyfall"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/undercover"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+	"github.com/aaronzipp/you-are-officially-sus/internal/store"
+)
+
+// newTestServer serves the JSON API and event stream of a fresh in-memory server
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	sse.SetBroker(sse.NewMemoryBroker())
+	ctx := &handlers.Context{
+		LobbyStore: store.NewMemoryStore(),
+		Profiles:   store.NewMemoryProfileStore(),
+		Templates:  template.New(""),
+		Modes: modes.NewRegistry(spyfall.New(
+			[]models.Location{{Word: "beach", Categories: []string{"leisure"}}, {Word: "bank", Categories: []string{"services"}}},
+			[]string{"Ask about the weather"},
+		)),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(api.Version+"/", ctx.HandleAPI)
+	mux.HandleFunc("/sse/", ctx.HandleSSE)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// streamItem is one value of Session.Events
+type streamItem struct {
+	ev  client.Event
+	err error
+}
+
+// watch ranges over the session's events in the background until the test ends
+func watch(t *testing.T, s *client.Session) <-chan streamItem {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	items := make(chan streamItem, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev, err := range s.Events(ctx) {
+			select {
+			case items <- streamItem{ev, err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return items
+}
+
+// next returns the next event of the stream, failing on errors
+func next(t *testing.T, items <-chan streamItem) client.Event {
+	t.Helper()
+	select {
+	case item := <-items:
+		if item.err != nil {
+			t.Fatalf("event stream: %v", item.err)
+		}
+		return item.ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+		return client.Event{}
+	}
+}
+
+// nextPayload returns the payload of the next event, which must be a T
+func nextPayload[T client.Payload](t *testing.T, items <-chan streamItem) (client.Event, T) {
+	t.Helper()
+	ev := next(t, items)
+	p, err := ev.Payload()
+	if err != nil {
+		t.Fatalf("decoding %s: %v", ev.Type, err)
+	}
+	typed, ok := p.(T)
+	if !ok {
+		var want T
+		t.Fatalf("got %s event %+v, want %s", ev.Type, p, want.Type())
+	}
+	return ev, typed
+}
+
+// readyAll marks the sessions ready and checks that the last of them moved the game on to phase
+func readyAll(t *testing.T, sessions []*client.Session, phase string) {
+	t.Helper()
+	for i, s := range sessions {
+		resp, err := s.Ready(context.Background())
+		if err != nil {
+			t.Fatalf("Ready: %v", err)
+		}
+		if last := i == len(sessions)-1; last && resp.NextPhase != phase {
+			t.Fatalf("last ready moved to %q, want %q", resp.NextPhase, phase)
+		}
+	}
+}
+
+func TestClientPlaysAGame(t *testing.T) {
+	srv := newTestServer(t)
+	// No idle connections, so dropping the event stream below does not break the next request
+	c := client.New(srv.URL, client.WithHTTPClient(&http.Client{Transport: &http.Transport{DisableKeepAlives: true}}))
+	ctx := context.Background()
+
+	host, lobby, err := c.CreateLobby(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("CreateLobby: %v", err)
+	}
+	if lobby.Phase != client.PhaseWaiting || lobby.HostID != host.PlayerID {
+		t.Fatalf("new lobby is in phase %q with host %q", lobby.Phase, lobby.HostID)
+	}
+	sessions := []*client.Session{host}
+	for _, name := range []string{"Bob", "Carol"} {
+		s, _, err := c.JoinLobby(ctx, lobby.Code, name)
+		if err != nil {
+			t.Fatalf("JoinLobby: %v", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if _, _, err := c.JoinLobby(ctx, lobby.Code, "Bob"); !isCode(err, client.CodeNameTaken) {
+		t.Fatalf("joining with a taken name returned %v, want %s", err, client.CodeNameTaken)
+	}
+
+	items := watch(t, host)
+	_, snap := nextPayload[client.Snapshot](t, items)
+	if snap.Phase != client.PhaseWaiting || len(snap.Players) != 3 {
+		t.Fatalf("snapshot has phase %q and %d players", snap.Phase, len(snap.Players))
+	}
+
+	// Only the host starts the game
+	if _, err := sessions[1].Start(ctx); !isCode(err, client.CodeForbidden) {
+		t.Fatalf("Start by a guest returned %v, want %s", err, client.CodeForbidden)
+	}
+	if _, err := host.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, p := nextPayload[client.PhaseChanged](t, items); p.Phase != client.PhaseReadyCheck {
+		t.Fatalf("game started in phase %q", p.Phase)
+	}
+
+	readyAll(t, sessions, client.PhaseRoleReveal)
+	for n := 1; n <= len(sessions); n++ {
+		if _, p := nextPayload[client.ReadyChanged](t, items); p.Count != n || p.Total != 3 {
+			t.Fatalf("ready count %d/%d, want %d/3", p.Count, p.Total, n)
+		}
+	}
+	if _, p := nextPayload[client.PhaseChanged](t, items); p.Phase != client.PhaseRoleReveal {
+		t.Fatalf("moved to phase %q, want %q", p.Phase, client.PhaseRoleReveal)
+	}
+
+	// Exactly one player is the spy
+	spyID := ""
+	for _, s := range sessions {
+		l, err := s.Lobby(ctx)
+		if err != nil {
+			t.Fatalf("Lobby: %v", err)
+		}
+		if l.Game == nil || l.Game.You.Role == nil {
+			t.Fatalf("player %s has no role in phase %q", s.PlayerID, l.Phase)
+		}
+		if l.Game.You.Role.Spy {
+			spyID = s.PlayerID
+		}
+	}
+	if spyID == "" {
+		t.Fatal("nobody is the spy")
+	}
+
+	readyAll(t, sessions, client.PhasePlaying)
+	// A majority is enough to start the vote
+	readyAll(t, sessions[:2], client.PhaseVoting)
+	var lastID int64
+	for {
+		ev := next(t, items)
+		lastID = ev.ID
+		if p, _ := ev.Payload(); p == (client.PhaseChanged{Phase: client.PhaseVoting, VoteRound: 1}) {
+			break
+		}
+	}
+
+	// The stream drops and everyone votes before it reconnects; it resumes after the last event it saw
+	srv.CloseClientConnections()
+	select {
+	case item := <-items:
+		if item.err == nil {
+			t.Fatalf("got %s event after the connection dropped, want an error", item.ev.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dropped connection was not reported")
+	}
+	for _, s := range sessions {
+		suspect := spyID
+		if s.PlayerID == spyID {
+			suspect = host.PlayerID
+			if spyID == host.PlayerID {
+				suspect = sessions[1].PlayerID
+			}
+		}
+		if _, err := s.Vote(ctx, suspect); err != nil {
+			t.Fatalf("Vote: %v", err)
+		}
+	}
+
+	for n := 1; n <= len(sessions); n++ {
+		ev, p := nextPayload[client.VoteCast](t, items)
+		if ev.ID <= lastID {
+			t.Fatalf("replayed event %d after event %d", ev.ID, lastID)
+		}
+		lastID = ev.ID
+		if p.Count != n {
+			t.Fatalf("vote count %d, want %d", p.Count, n)
+		}
+	}
+	if _, p := nextPayload[client.GameFinished](t, items); !p.InnocentWon || p.MostVoted != spyID {
+		t.Fatalf("game finished with %+v, want the innocents to find %s", p, spyID)
+	}
+	if _, snap := nextPayload[client.Snapshot](t, items); snap.Phase != client.PhaseFinished {
+		t.Fatalf("snapshot after reconnecting has phase %q", snap.Phase)
+	}
+
+	results, err := sessions[1].Results(ctx)
+	if err != nil {
+		t.Fatalf("Results: %v", err)
+	}
+	if !results.Game.InnocentsWon || results.Game.VotedOut != spyID || len(results.Game.Players) != 3 {
+		t.Fatalf("results %+v", results.Game)
+	}
+}
+
+func TestClientEventsEndForUnknownToken(t *testing.T) {
+	srv := newTestServer(t)
+	c := client.New(srv.URL)
+
+	host, _, err := c.CreateLobby(context.Background(), "Alice")
+	if err != nil {
+		t.Fatalf("CreateLobby: %v", err)
+	}
+	s := c.Resume(host.Code, host.PlayerID, "not-the-token")
+
+	// The error is reported once and the stream stops instead of reconnecting
+	var errs []error
+	for _, err := range s.Events(context.Background()) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !isCode(errs[0], client.CodeUnauthorized) && !isStatus(errs[0], http.StatusUnauthorized) {
+		t.Fatalf("got errors %v, want one unauthorized error", errs)
+	}
+}
+
+// isCode reports whether err is an API error with the given code
+func isCode(err error, code string) bool {
+	var apiErr *client.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// isStatus reports whether err is an error response with the given HTTP status
+func isStatus(err error, status int) bool {
+	var apiErr *client.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// TestWireTypesMatchServer guards the copies of the server's documents against drifting apart
+func TestWireTypesMatchServer(t *testing.T) {
+	pairs := []struct{ client, server any }{
+		{client.Lobby{}, api.Lobby{}},
+		{client.Player{}, api.Player{}},
+		{client.Game{}, api.Game{}},
+		{client.You{}, api.You{}},
+		{client.Role{}, api.Role{}},
+		{client.Detail{}, api.Detail{}},
+		{client.ReadyResponse{}, api.ReadyResponse{}},
+		{client.VoteResponse{}, api.VoteResponse{}},
+		{client.Results{}, api.Results{}},
+		{client.Score{}, export.Score{}},
+		{client.FinishedGame{}, export.Game{}},
+		{client.FinishedPlayer{}, export.Player{}},
+		{client.Ballot{}, export.Ballot{}},
+		{client.Secret{}, export.Secret{}},
+		{client.PlayerJoined{}, events.PlayerJoined{}},
+		{client.PlayerLeft{}, events.PlayerLeft{}},
+		{client.HostChanged{}, events.HostChanged{}},
+		{client.SettingsChanged{}, events.SettingsChanged{}},
+		{client.PhaseChanged{}, events.PhaseChanged{}},
+		{client.ReadyChanged{}, events.ReadyChanged{}},
+		{client.VoteCast{}, events.VoteCast{}},
+		{client.GameFinished{}, events.GameFinished{}},
+		{client.GameAborted{}, events.GameAborted{}},
+		{client.LobbyClosed{}, events.LobbyClosed{}},
+		{client.ServerNotice{}, events.ServerNotice{}},
+		{client.Snapshot{}, events.Snapshot{}},
+		{client.SnapshotPlayer{}, events.SnapshotPlayer{}},
+	}
+	for _, pair := range pairs {
+		got, want := jsonFields(reflect.TypeOf(pair.client)), jsonFields(reflect.TypeOf(pair.server))
+		if !slices.Equal(got, want) {
+			t.Errorf("%T has JSON fields %v, %T has %v", pair.client, got, pair.server, want)
+		}
+	}
+	for _, pair := range []struct{ client, server string }{
+		{client.CodeBadRequest, api.CodeBadRequest},
+		{client.CodeUnauthorized, api.CodeUnauthorized},
+		{client.CodeForbidden, api.CodeForbidden},
+		{client.CodeNotFound, api.CodeNotFound},
+		{client.CodeMethodNotAllowed, api.CodeMethodNotAllowed},
+		{client.CodeNameTaken, api.CodeNameTaken},
+		{client.CodeGameInProgress, api.CodeGameInProgress},
+		{client.CodeWrongPhase, api.CodeWrongPhase},
+		{client.CodeUnavailable, api.CodeUnavailable},
+		{client.PhaseWaiting, string(models.StatusWaiting)},
+		{client.PhaseReadyCheck, string(models.StatusReadyCheck)},
+		{client.PhaseRoleReveal, string(models.StatusRoleReveal)},
+		{client.PhasePlaying, string(models.StatusPlaying)},
+		{client.PhaseVoting, string(models.StatusVoting)},
+		{client.PhaseFinished, string(models.StatusFinished)},
+		{client.ModeSpyfall, string(spyfall.ID)},
+		{client.ModeUndercover, string(undercover.ID)},
+	} {
+		if pair.client != pair.server {
+			t.Errorf("client constant %q, server has %q", pair.client, pair.server)
+		}
+	}
+}
+
+// jsonFields lists the JSON tags of a struct's fields with the kind of each field
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		fields = append(fields, f.Tag.Get("json")+" "+f.Type.Kind().String())
+	}
+	return fields
+}
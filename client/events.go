@@ -0,0 +1,292 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Payload is the typed data of an event, one of the types below
+type Payload interface {
+	// Type is the name of the event, e.g. "player_joined"
+	Type() string
+}
+
+// PlayerJoined is sent when a player or bot joins (or rejoins) the lobby
+type PlayerJoined struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Bot      bool   `json:"bot,omitempty"`
+	Rejoin   bool   `json:"rejoin,omitempty"` // the player was already in the lobby
+}
+
+// PlayerLeft is sent when a player leaves, disconnects for good or a bot is removed
+type PlayerLeft struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Bot      bool   `json:"bot,omitempty"`
+	Kicked   bool   `json:"kicked,omitempty"` // removed by an operator
+}
+
+// HostChanged is sent when the lobby gets a new host
+type HostChanged struct {
+	HostID    string `json:"host_id"`
+	Automatic bool   `json:"automatic"` // picked by the server rather than by the previous host
+}
+
+// SettingsChanged is sent when the host picks another mode or mode options
+type SettingsChanged struct {
+	Mode    string          `json:"mode"`
+	Options map[string]bool `json:"options,omitempty"`
+}
+
+// PhaseChanged is sent when a game starts, moves to its next phase, starts a revote
+// or the lobby returns to the waiting room
+type PhaseChanged struct {
+	Phase     string `json:"phase"`
+	VoteRound int    `json:"vote_round,omitempty"` // set for the voting phase
+}
+
+// ReadyChanged is sent when the number of ready players of a phase changes
+type ReadyChanged struct {
+	Phase    string `json:"phase"`
+	PlayerID string `json:"player_id,omitempty"` // empty when a leaving player changed the count
+	Ready    bool   `json:"ready"`
+	Count    int    `json:"count"`
+	Total    int    `json:"total"`
+}
+
+// VoteCast is sent when the number of votes in the current round changes
+// Ballots stay secret until the game is finished
+type VoteCast struct {
+	Round    int    `json:"round"`
+	PlayerID string `json:"player_id,omitempty"` // empty when a leaving player changed the count
+	Count    int    `json:"count"`
+	Total    int    `json:"total"`
+}
+
+// GameFinished is sent when the game has a result
+type GameFinished struct {
+	InnocentWon  bool   `json:"innocent_won"`
+	SpyForfeited bool   `json:"spy_forfeited,omitempty"`
+	MostVoted    string `json:"most_voted,omitempty"` // empty on a tie or forfeit
+	IsTie        bool   `json:"is_tie,omitempty"`
+}
+
+// GameAborted is sent when a game ends without a result; the lobby returns to the waiting room shortly after
+type GameAborted struct {
+	Reason string `json:"reason"`
+}
+
+// LobbyClosed is sent when the host closes the lobby or it expires
+type LobbyClosed struct {
+	Reason string `json:"reason"` // "closed", "expired" or "admin"
+}
+
+// ServerNotice is sent to every lobby when an operator sets or clears the maintenance message
+type ServerNotice struct {
+	Message string `json:"message"` // empty when the notice was cleared
+}
+
+// Snapshot is the current state of the lobby, sent first on every connection
+type Snapshot struct {
+	Phase     string           `json:"phase"`
+	HostID    string           `json:"host_id"`
+	Mode      string           `json:"mode"`
+	Players   []SnapshotPlayer `json:"players"`
+	VoteRound int              `json:"vote_round,omitempty"`
+	Ready     int              `json:"ready"`            // ready players (ready check, role reveal and playing)
+	Votes     int              `json:"votes"`            // votes cast (voting)
+	Notice    string           `json:"notice,omitempty"` // the current maintenance message
+}
+
+// SnapshotPlayer is a player of a Snapshot
+type SnapshotPlayer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Bot  bool   `json:"bot,omitempty"`
+}
+
+func (PlayerJoined) Type() string    { return "player_joined" }
+func (PlayerLeft) Type() string      { return "player_left" }
+func (HostChanged) Type() string     { return "host_changed" }
+func (SettingsChanged) Type() string { return "settings_changed" }
+func (PhaseChanged) Type() string    { return "phase_changed" }
+func (ReadyChanged) Type() string    { return "ready_changed" }
+func (VoteCast) Type() string        { return "vote_cast" }
+func (GameFinished) Type() string    { return "game_finished" }
+func (GameAborted) Type() string     { return "game_aborted" }
+func (LobbyClosed) Type() string     { return "lobby_closed" }
+func (ServerNotice) Type() string    { return "server_notice" }
+func (Snapshot) Type() string        { return "snapshot" }
+
+// reconnectDelay is the pause before an event stream reconnects after it dropped
+const reconnectDelay = time.Second
+
+// maxEventSize bounds one event of the stream
+const maxEventSize = 1 << 20
+
+// Event is one event of a lobby's stream
+type Event struct {
+	ID    int64 // 0 for the snapshot, which is not numbered
+	Type  string
+	Lobby string
+	Time  time.Time
+	Data  json.RawMessage // the payload, decode it with Payload
+}
+
+// Payload decodes the event into its typed payload, e.g. a PhaseChanged value
+// Types added by a newer server decode to nil without an error.
+func (e Event) Payload() (Payload, error) {
+	switch e.Type {
+	case "snapshot":
+		return decodePayload[Snapshot](e)
+	case "player_joined":
+		return decodePayload[PlayerJoined](e)
+	case "player_left":
+		return decodePayload[PlayerLeft](e)
+	case "host_changed":
+		return decodePayload[HostChanged](e)
+	case "settings_changed":
+		return decodePayload[SettingsChanged](e)
+	case "phase_changed":
+		return decodePayload[PhaseChanged](e)
+	case "ready_changed":
+		return decodePayload[ReadyChanged](e)
+	case "vote_cast":
+		return decodePayload[VoteCast](e)
+	case "game_finished":
+		return decodePayload[GameFinished](e)
+	case "game_aborted":
+		return decodePayload[GameAborted](e)
+	case "lobby_closed":
+		return decodePayload[LobbyClosed](e)
+	case "server_notice":
+		return decodePayload[ServerNotice](e)
+	}
+	return nil, nil
+}
+
+// decodePayload unmarshals the data of e into a T
+func decodePayload[T Payload](e Event) (Payload, error) {
+	var p T
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		return nil, fmt.Errorf("decoding %s event: %w", e.Type, err)
+	}
+	return p, nil
+}
+
+// Events streams the lobby's events until ctx is done or the loop breaks
+// Every connection starts with a snapshot of the lobby. A dropped connection is reported as an
+// error and reopened after a moment, resuming after the last event seen; keep ranging to
+// reconnect or break to stop. The stream ends after an error that reconnecting cannot fix,
+// such as a revoked token or a deleted lobby.
+func (s *Session) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		var lastID int64
+		for {
+			stopped, err := s.stream(ctx, lastID, func(ev Event) bool {
+				if ev.ID != 0 {
+					lastID = ev.ID
+				}
+				return yield(ev, nil)
+			})
+			if stopped || ctx.Err() != nil {
+				return
+			}
+
+			var apiErr *Error
+			permanent := errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError
+			if !yield(Event{}, err) || permanent {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}
+}
+
+// stream reads one connection of the event stream, passing every event to fn
+// stopped reports that fn asked to stop; otherwise err says why the connection ended
+func (s *Session) stream(ctx context.Context, lastID int64, fn func(Event) bool) (stopped bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.client.baseURL+"/sse/"+s.Code+"?format=json", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	if lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastID, 10))
+	}
+
+	resp, err := s.client.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, responseError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	var id int64
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			// A blank line ends the event
+			if data.Len() > 0 {
+				ev, err := decodeEvent(id, data.String())
+				if err != nil {
+					return false, err
+				}
+				if !fn(ev) {
+					return true, nil
+				}
+			}
+			id = 0
+			data.Reset()
+		case field == "":
+			// Comment, e.g. the server's heartbeat
+		case field == "id":
+			id, _ = strconv.ParseInt(value, 10, 64)
+		case field == "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("reading event stream: %w", err)
+	}
+	return false, errors.New("event stream closed by the server")
+}
+
+// decodeEvent parses the JSON envelope of an event
+func decodeEvent(id int64, data string) (Event, error) {
+	var env struct {
+		Type  string          `json:"type"`
+		Lobby string          `json:"lobby"`
+		Time  time.Time       `json:"time"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(data), &env); err != nil {
+		return Event{}, fmt.Errorf("decoding event %d: %w", id, err)
+	}
+	return Event{ID: id, Type: env.Type, Lobby: env.Lobby, Time: env.Time, Data: env.Data}, nil
+}
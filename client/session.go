@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Session is one player in one lobby
+// Keep Code, PlayerID and Token to resume it later with Client.Resume.
+type Session struct {
+	Code     string
+	PlayerID string
+	Token    string
+
+	client *Client
+}
+
+// Lobby returns the current state of the lobby, including this player's role during a game
+func (s *Session) Lobby(ctx context.Context) (*Lobby, error) {
+	var lobby Lobby
+	if err := s.do(ctx, http.MethodGet, "", nil, &lobby); err != nil {
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+// Start starts a game (host only)
+func (s *Session) Start(ctx context.Context) (*Lobby, error) {
+	var lobby Lobby
+	if err := s.do(ctx, http.MethodPost, "/start", nil, &lobby); err != nil {
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+// Restart drops the current game and returns everyone to the lobby (host only)
+func (s *Session) Restart(ctx context.Context) (*Lobby, error) {
+	var lobby Lobby
+	if err := s.do(ctx, http.MethodPost, "/restart", nil, &lobby); err != nil {
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+// Ready toggles this player's readiness for the current phase
+func (s *Session) Ready(ctx context.Context) (*ReadyResponse, error) {
+	var resp ReadyResponse
+	if err := s.do(ctx, http.MethodPost, "/ready", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Vote votes for the player with ID suspect
+func (s *Session) Vote(ctx context.Context, suspect string) (*VoteResponse, error) {
+	var resp VoteResponse
+	if err := s.do(ctx, http.MethodPost, "/vote", voteRequest{Suspect: suspect}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Results returns the outcome of the finished game
+func (s *Session) Results(ctx context.Context) (*Results, error) {
+	var results Results
+	if err := s.do(ctx, http.MethodGet, "/results", nil, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// Leave leaves the lobby; the token stops working
+// A leaving host may name newHostID as successor ("" lets the server pick)
+func (s *Session) Leave(ctx context.Context, newHostID string) error {
+	return s.do(ctx, http.MethodPost, "/leave", leaveRequest{NewHostID: newHostID}, nil)
+}
+
+// do sends an authenticated request for this session's lobby
+func (s *Session) do(ctx context.Context, method, action string, body, out any) error {
+	return s.client.do(ctx, method, "/lobbies/"+s.Code+action, s.Token, body, out)
+}
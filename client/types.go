@@ -0,0 +1,183 @@
+package client
+
+import "time"
+
+// Phases of Lobby.Phase and Game.Phase
+const (
+	PhaseWaiting    = "waiting" // in the lobby between games
+	PhaseReadyCheck = "ready_check"
+	PhaseRoleReveal = "role_reveal"
+	PhasePlaying    = "playing"
+	PhaseVoting     = "voting"
+	PhaseFinished   = "finished"
+)
+
+// Game modes of Lobby.Mode
+const (
+	ModeSpyfall    = "spyfall"
+	ModeUndercover = "undercover"
+)
+
+// Error codes of Error.Code
+const (
+	CodeBadRequest       = "bad_request"        // malformed body or missing field
+	CodeUnauthorized     = "unauthorized"       // no valid token
+	CodeForbidden        = "forbidden"          // e.g. only the host can start the game
+	CodeNotFound         = "not_found"          // unknown lobby or route
+	CodeMethodNotAllowed = "method_not_allowed" // wrong HTTP method for the route
+	CodeNameTaken        = "name_taken"         // another player in the lobby has that name
+	CodeGameInProgress   = "game_in_progress"   // the action needs the lobby to be waiting
+	CodeWrongPhase       = "wrong_phase"        // the action does not fit the current game phase
+	CodeUnavailable      = "unavailable"        // the server is shutting down
+)
+
+// Lobby is the state of a lobby as seen by one player
+type Lobby struct {
+	Code    string          `json:"code"`
+	Phase   string          `json:"phase"` // PhaseWaiting or a game phase
+	HostID  string          `json:"host_id"`
+	Mode    string          `json:"mode"`
+	Options map[string]bool `json:"options"`
+	Players []Player        `json:"players"`
+	Scores  []Score         `json:"scores"`
+	Game    *Game           `json:"game,omitempty"` // nil while waiting
+}
+
+// Player is a member of a lobby
+type Player struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Bot  bool   `json:"bot,omitempty"`
+}
+
+// Game is the game in progress as seen by one player
+type Game struct {
+	Phase           string `json:"phase"`
+	VoteRound       int    `json:"vote_round"`
+	Ready           int    `json:"ready"` // ready players of the ready check, role reveal or playing phase
+	Votes           int    `json:"votes"` // votes cast in the current round
+	Total           int    `json:"total"`
+	FirstQuestioner string `json:"first_questioner,omitempty"` // player name
+	You             You    `json:"you"`
+}
+
+// You is the requesting player's part of a game
+type You struct {
+	Ready bool  `json:"ready"`
+	Voted bool  `json:"voted"`
+	Role  *Role `json:"role,omitempty"` // nil before the role reveal
+}
+
+// Role is the secret a player sees on their role card
+type Role struct {
+	Title    string   `json:"title"`
+	Spy      bool     `json:"spy"` // on the hidden team
+	Details  []Detail `json:"details"`
+	Hint     string   `json:"hint,omitempty"`
+	Reminder *Detail  `json:"reminder,omitempty"`
+}
+
+// Detail is a labelled secret, e.g. the location
+type Detail struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Score is a player's record in the lobby
+type Score struct {
+	PlayerID       string   `json:"player_id"`
+	Name           string   `json:"name"`
+	Bot            bool     `json:"bot"`
+	Wins           int      `json:"wins"`
+	Losses         int      `json:"losses"`
+	SpyRating      *float64 `json:"spy_rating"` // nil before the first spy game
+	SpyGames       int      `json:"spy_games"`
+	InnocentRating *float64 `json:"innocent_rating"` // nil before the first innocent game
+	InnocentGames  int      `json:"innocent_games"`
+}
+
+// ReadyResponse is the outcome of Session.Ready
+type ReadyResponse struct {
+	Phase     string `json:"phase"`                // the phase the readiness applied to
+	Ready     bool   `json:"ready"`                // the player's readiness after the toggle
+	NextPhase string `json:"next_phase,omitempty"` // set when everyone was ready and the game moved on
+}
+
+// VoteResponse is the outcome of Session.Vote
+type VoteResponse struct {
+	Round    int  `json:"round"`    // the round the vote was cast in
+	Revote   bool `json:"revote"`   // the round ended in a tie and a new one started
+	Finished bool `json:"finished"` // the vote decided the game
+}
+
+// Results is the outcome of a finished game
+type Results struct {
+	Game   FinishedGame `json:"game"`
+	Scores []Score      `json:"scores"`
+}
+
+// FinishedGame is one finished game
+type FinishedGame struct {
+	Number         int              `json:"number"`
+	Mode           string           `json:"mode"`
+	StartedAt      *time.Time       `json:"started_at"`
+	FinishedAt     time.Time        `json:"finished_at"`
+	Secrets        []Secret         `json:"secrets"`                   // what the game was played with, e.g. the location
+	Location       string           `json:"location,omitempty"`        // Spyfall only, also in Secrets
+	CivilianWord   string           `json:"civilian_word,omitempty"`   // Undercover only, also in Secrets
+	UndercoverWord string           `json:"undercover_word,omitempty"` // Undercover only, also in Secrets
+	InnocentsWon   bool             `json:"innocents_won"`
+	Tie            bool             `json:"tie"`
+	SpyForfeited   bool             `json:"spy_forfeited"`
+	VotedOut       string           `json:"voted_out,omitempty"` // player ID
+	Players        []FinishedPlayer `json:"players"`
+	VoteRounds     [][]Ballot       `json:"vote_rounds"` // tie-break rounds first, the deciding round last
+}
+
+// Secret is a labelled secret of a finished game
+type Secret struct {
+	Key   string `json:"key"` // stable within the mode, e.g. "location"
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// FinishedPlayer is a participant of a finished game
+type FinishedPlayer struct {
+	PlayerID     string  `json:"player_id"`
+	Name         string  `json:"name"`
+	Role         string  `json:"role"`
+	Impostor     bool    `json:"impostor"`
+	Left         bool    `json:"left"` // forfeiting spy
+	Won          bool    `json:"won"`
+	RatingChange float64 `json:"rating_change"`
+}
+
+// Ballot is one vote in a voting round
+type Ballot struct {
+	Voter   string `json:"voter"`   // player ID
+	Suspect string `json:"suspect"` // player ID
+}
+
+// Request and response bodies that callers never see
+type (
+	nameRequest struct {
+		Name string `json:"name"`
+	}
+	leaveRequest struct {
+		NewHostID string `json:"new_host_id,omitempty"`
+	}
+	voteRequest struct {
+		Suspect string `json:"suspect"`
+	}
+	sessionResponse struct {
+		Token    string `json:"token"`
+		PlayerID string `json:"player_id"`
+		Lobby    Lobby  `json:"lobby"`
+	}
+	errorResponse struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+)
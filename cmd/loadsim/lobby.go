@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runLobby creates one lobby, connects its players and plays the configured number of games
+func runLobby(cfg config, index int, st *stats) error {
+	players := make([]*player, cfg.players)
+	for i := range players {
+		players[i] = newPlayer(cfg.baseURL, fmt.Sprintf("sim-%d-%d", index, i))
+	}
+	host := players[0]
+
+	redirect, err := host.post("/create", url.Values{"name": {host.name}})
+	if err != nil {
+		return err
+	}
+	roomCode := strings.TrimPrefix(redirect, "/lobby/")
+	if roomCode == "" || roomCode == redirect {
+		return fmt.Errorf("unexpected create redirect %q", redirect)
+	}
+	for _, p := range players[1:] {
+		if _, err := p.post("/join/"+roomCode, url.Values{"name": {p.name}}); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range players {
+		if err := p.connect(roomCode); err != nil {
+			return err
+		}
+		defer p.close()
+	}
+
+	sim := &lobbySim{cfg: cfg, roomCode: roomCode, players: players, stats: st}
+	for range cfg.games {
+		if err := sim.playGame(); err != nil {
+			return err
+		}
+	}
+
+	// Closing the lobby also exercises the final nav-redirect to "/"
+	return sim.transition("/", sim.hostPost("/close-lobby/"+roomCode))
+}
+
+// lobbySim drives a single lobby through full games
+type lobbySim struct {
+	cfg      config
+	roomCode string
+	players  []*player
+	stats    *stats
+}
+
+// playGame runs start -> ready check -> role reveal -> playing -> voting -> results -> restart
+func (s *lobbySim) playGame() error {
+	code := s.roomCode
+
+	if err := s.transition("/game/"+code+"/confirm-reveal", s.hostPost("/start-game/"+code)); err != nil {
+		return err
+	}
+	for _, next := range []string{"/game/" + code + "/roles", "/game/" + code + "/play", "/game/" + code + "/voting"} {
+		if err := s.transition(next, s.readyUntilAdvance); err != nil {
+			return err
+		}
+	}
+	if err := s.transition("/results/"+code, s.voteUnanimously); err != nil {
+		return err
+	}
+	return s.transition("/lobby/"+code, s.hostPost("/restart-game/"+code))
+}
+
+// hostPost returns an action that sends a single POST as the host
+func (s *lobbySim) hostPost(path string) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		sent := time.Now()
+		_, err := s.players[0].post(path, nil)
+		return sent, err
+	}
+}
+
+// readyUntilAdvance readies players one by one until the phase advances
+func (s *lobbySim) readyUntilAdvance() (time.Time, error) {
+	for _, p := range s.players {
+		sent := time.Now()
+		redirect, err := p.post("/game/"+s.roomCode+"/ready", nil)
+		if err != nil {
+			return sent, err
+		}
+		if redirect != "" {
+			return sent, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("phase did not advance after all players were ready")
+}
+
+// voteUnanimously makes everyone vote for the same player so no tie-break round is needed
+func (s *lobbySim) voteUnanimously() (time.Time, error) {
+	ids := make([]string, len(s.players))
+	for i, p := range s.players {
+		ids[i] = p.id()
+	}
+	sort.Strings(ids)
+
+	var sent time.Time
+	for _, p := range s.players {
+		suspect := ids[0]
+		if suspect == p.id() {
+			suspect = ids[1]
+		}
+		sent = time.Now()
+		if _, err := p.post("/game/"+s.roomCode+"/vote", url.Values{"suspect": {suspect}}); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// transition performs the action that triggers a phase change and measures nav-redirect delivery
+// The action returns when its triggering request was sent, so latency covers the server's fan-out
+func (s *lobbySim) transition(path string, action func() (time.Time, error)) error {
+	sent, err := action()
+	if err != nil {
+		s.stats.addError()
+		return err
+	}
+	s.stats.addTransition()
+
+	deadline := time.Now().Add(s.cfg.wait)
+	var wg sync.WaitGroup
+	for _, p := range s.players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if at, ok := p.waitFor(path, deadline); ok {
+				s.stats.addDelivery(at.Sub(sent))
+			} else {
+				s.stats.addDrop()
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
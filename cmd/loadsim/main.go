@@ -0,0 +1,153 @@
+// Command loadsim plays full games against a running server to measure SSE fan-out.
+//
+// It creates N lobbies with M players each, keeps one SSE stream open per player,
+// drives every phase through the real HTTP endpoints and reports how long it takes
+// for nav-redirect events to reach each client.
+//
+//	go run ./cmd/loadsim -url http://localhost:8080 -lobbies 50 -players 6 -games 3
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "base URL of the running server")
+	flag.IntVar(&cfg.lobbies, "lobbies", 10, "number of concurrent lobbies")
+	flag.IntVar(&cfg.players, "players", 4, "players (SSE clients) per lobby, at least 3")
+	flag.IntVar(&cfg.games, "games", 1, "games to play per lobby")
+	flag.DurationVar(&cfg.wait, "wait", 10*time.Second, "how long to wait for a nav-redirect before counting it as dropped")
+	flag.IntVar(&cfg.serverPID, "pid", 0, "server process ID to report resident memory for (Linux only)")
+	flag.Parse()
+
+	if cfg.players < 3 {
+		log.Fatal("-players must be at least 3 (game.MinPlayers)")
+	}
+	cfg.baseURL = strings.TrimRight(cfg.baseURL, "/")
+
+	stats := &stats{}
+	started := time.Now()
+
+	var wg sync.WaitGroup
+	for i := range cfg.lobbies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runLobby(cfg, i, stats); err != nil {
+				stats.addError()
+				log.Printf("lobby %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats.report(os.Stdout, cfg, time.Since(started))
+}
+
+// config holds the command line settings
+type config struct {
+	baseURL   string
+	lobbies   int
+	players   int
+	games     int
+	wait      time.Duration
+	serverPID int
+}
+
+// stats collects delivery measurements from all lobbies
+type stats struct {
+	mu          sync.Mutex
+	transitions int
+	expected    int
+	delivered   int
+	dropped     int
+	errors      int
+	latencies   []time.Duration
+}
+
+// addDelivery records a nav-redirect that reached a client
+func (s *stats) addDelivery(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected++
+	s.delivered++
+	s.latencies = append(s.latencies, latency)
+}
+
+// addDrop records a nav-redirect that never reached a client
+func (s *stats) addDrop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected++
+	s.dropped++
+}
+
+// addTransition records a phase transition
+func (s *stats) addTransition() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions++
+}
+
+// addError records a failed request or lobby
+func (s *stats) addError() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+}
+
+// report prints the summary
+func (s *stats) report(w *os.File, cfg config, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	pct := func(p float64) time.Duration {
+		if len(s.latencies) == 0 {
+			return 0
+		}
+		return s.latencies[int(float64(len(s.latencies)-1)*p)]
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	fmt.Fprintf(w, "lobbies=%d players/lobby=%d games/lobby=%d elapsed=%s\n", cfg.lobbies, cfg.players, cfg.games, elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "phase transitions:     %d\n", s.transitions)
+	fmt.Fprintf(w, "nav-redirect expected: %d\n", s.expected)
+	fmt.Fprintf(w, "nav-redirect received: %d\n", s.delivered)
+	fmt.Fprintf(w, "nav-redirect dropped:  %d\n", s.dropped)
+	fmt.Fprintf(w, "errors:                %d\n", s.errors)
+	fmt.Fprintf(w, "latency p50=%s p90=%s p99=%s max=%s\n", pct(0.50), pct(0.90), pct(0.99), pct(1))
+	fmt.Fprintf(w, "simulator memory: heap=%.1fMiB sys=%.1fMiB goroutines=%d\n", float64(mem.HeapAlloc)/(1<<20), float64(mem.Sys)/(1<<20), runtime.NumGoroutine())
+	if cfg.serverPID > 0 {
+		if rss, err := residentMemory(cfg.serverPID); err == nil {
+			fmt.Fprintf(w, "server memory: rss=%s\n", rss)
+		} else {
+			fmt.Fprintf(w, "server memory: unavailable (%v)\n", err)
+		}
+	}
+}
+
+// residentMemory reads VmRSS for a process from /proc
+func residentMemory(pid int) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "VmRSS:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "VmRSS:")), nil
+		}
+	}
+	return "", fmt.Errorf("VmRSS not found")
+}
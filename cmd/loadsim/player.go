@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// transport is shared by all simulated players so connections are pooled
+var transport = &http.Transport{
+	MaxIdleConns:        10000,
+	MaxIdleConnsPerHost: 10000,
+	IdleConnTimeout:     30 * time.Second,
+}
+
+// redirectTarget extracts the destination from a redirect snippet
+var redirectTarget = regexp.MustCompile(`redirect\?to=([^"&]+)`)
+
+// navEvent is a nav-redirect received on a player's SSE stream
+type navEvent struct {
+	path string
+	at   time.Time
+}
+
+// player is one simulated browser with its own cookie jar and SSE stream
+type player struct {
+	name    string
+	baseURL string
+	client  *http.Client
+	nav     chan navEvent
+	cancel  context.CancelFunc
+}
+
+// newPlayer creates a player with an empty cookie jar
+func newPlayer(baseURL, name string) *player {
+	jar, _ := cookiejar.New(nil)
+	return &player{
+		name:    name,
+		baseURL: baseURL,
+		client: &http.Client{
+			Jar:       jar,
+			Transport: transport,
+			Timeout:   30 * time.Second,
+			// Keep redirects visible, the server answers HTMX requests with HX-Redirect
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		nav: make(chan navEvent, 64),
+	}
+}
+
+// id returns the player_id cookie assigned by the server
+func (p *player) id() string {
+	u, _ := url.Parse(p.baseURL)
+	for _, c := range p.client.Jar.Cookies(u) {
+		if c.Name == "player_id" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// post sends a form POST and returns the HX-Redirect header (if any)
+func (p *player) post(path string, form url.Values) (string, error) {
+	resp, err := p.client.PostForm(p.baseURL+path, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("POST %s: %s", path, resp.Status)
+	}
+	return resp.Header.Get("HX-Redirect"), nil
+}
+
+// connect opens the SSE stream and returns once the server has sent its initial events
+func (p *player) connect(roomCode string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/sse/"+roomCode, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// SSE streams are long-lived, so they bypass the client timeout
+	streamClient := *p.client
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("SSE %s: %s", roomCode, resp.Status)
+	}
+
+	connected := make(chan struct{})
+	go p.readEvents(resp.Body, connected)
+
+	select {
+	case <-connected:
+		return nil
+	case <-time.After(10 * time.Second):
+		cancel()
+		return fmt.Errorf("SSE %s: no initial event", roomCode)
+	}
+}
+
+// readEvents parses the SSE stream and forwards nav-redirect events
+func (p *player) readEvents(body io.ReadCloser, connected chan struct{}) {
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	event := ""
+	var data strings.Builder
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data.WriteString(strings.TrimPrefix(line, "data: "))
+		case line == "":
+			if event == "" {
+				continue
+			}
+			if first {
+				close(connected)
+				first = false
+			}
+			if event == "nav-redirect" {
+				if m := redirectTarget.FindStringSubmatch(data.String()); m != nil {
+					path, _ := url.QueryUnescape(html.UnescapeString(m[1]))
+					select {
+					case p.nav <- navEvent{path: path, at: time.Now()}:
+					default:
+						// Test harness is not keeping up; the transition wait will count it as dropped
+					}
+				}
+			}
+			event = ""
+			data.Reset()
+		}
+	}
+}
+
+// waitFor blocks until a nav-redirect to path arrives or the deadline passes
+func (p *player) waitFor(path string, deadline time.Time) (time.Time, bool) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case ev := <-p.nav:
+			if ev.path == path {
+				return ev.at, true
+			}
+			// Stale or unrelated redirect (e.g. from an earlier phase), keep waiting
+		case <-timer.C:
+			return time.Time{}, false
+		}
+	}
+}
+
+// close terminates the SSE stream
+func (p *player) close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
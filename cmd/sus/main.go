@@ -0,0 +1,112 @@
+// Command sus plays a lobby from the terminal, next to players in the browser.
+//
+// Without a room code it creates a new lobby and prints the code to share:
+//
+//	go run ./cmd/sus -name Alice
+//	go run ./cmd/sus -name Bob ABC123
+//
+// The role card is only printed in this terminal. Ready and vote counts update live
+// from the lobby's event stream; type help for the commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/client"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "base URL of the server")
+	name := flag.String("name", os.Getenv("USER"), "your display name")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: sus [flags] [room code]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	log.SetFlags(0)
+	if strings.TrimSpace(*name) == "" {
+		log.Fatal("-name is required")
+	}
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.New(*baseURL)
+	var s *client.Session
+	var err error
+	if code := strings.ToUpper(strings.TrimSpace(flag.Arg(0))); code != "" {
+		s, _, err = c.JoinLobby(ctx, code, *name)
+	} else {
+		s, _, err = c.CreateLobby(ctx, *name)
+		if err == nil {
+			fmt.Printf("Created lobby %s. Others join with that code or at %s/join/%s\n", s.Code, strings.TrimRight(*baseURL, "/"), s.Code)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t := newTerminal(ctx, s, os.Stdout)
+	closed := t.run(readLines(ctx), watchEvents(ctx, s))
+	if !closed {
+		// Leave so the others are not left waiting for us
+		leaveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Leave(leaveCtx, ""); err != nil {
+			log.Printf("leaving the lobby: %v", err)
+		}
+	}
+}
+
+// streamItem is one item of the event stream
+type streamItem struct {
+	ev  client.Event
+	err error
+}
+
+// watchEvents forwards the session's events until ctx is done or the stream ends for good
+func watchEvents(ctx context.Context, s *client.Session) <-chan streamItem {
+	ch := make(chan streamItem)
+	go func() {
+		defer close(ch)
+		for ev, err := range s.Events(ctx) {
+			select {
+			case ch <- streamItem{ev, err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// readLines forwards the lines typed on stdin until it is closed
+func readLines(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			select {
+			case ch <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
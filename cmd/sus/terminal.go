@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aaronzipp/you-are-officially-sus/client"
+)
+
+const helpText = `Commands:
+  ready, r          toggle your readiness (ready check, role reveal, playing)
+  vote, v <n|name>  vote for a suspect
+  players, p        list the players
+  role              show your role card again
+  results           show the result of the last game
+  start             start a game (host)
+  restart           return everyone to the lobby (host)
+  quit, q           leave the lobby and exit`
+
+// terminal plays one session from the command line
+// Commands and events are handled on a single goroutine, so it needs no locking.
+type terminal struct {
+	ctx        context.Context
+	s          *client.Session
+	out        io.Writer
+	lobby      *client.Lobby   // last fetched state, nil before the first snapshot
+	candidates []client.Player // numbered suspects of the current vote
+}
+
+func newTerminal(ctx context.Context, s *client.Session, out io.Writer) *terminal {
+	return &terminal{ctx: ctx, s: s, out: out}
+}
+
+// run handles typed lines and events until the player quits or the lobby is gone
+// It reports whether the session already ended on the server (closed lobby, revoked token).
+func (t *terminal) run(lines <-chan string, stream <-chan streamItem) (closed bool) {
+	t.printf("Type help for the commands.\n")
+	for {
+		select {
+		case <-t.ctx.Done():
+			return false
+		case line, ok := <-lines:
+			if !ok || t.command(line) {
+				return false
+			}
+		case item, ok := <-stream:
+			if !ok {
+				return true
+			}
+			if item.err != nil {
+				var apiErr *client.Error
+				if errors.As(item.err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+					t.printf("Disconnected: %s\n", apiErr.Message)
+				} else {
+					t.printf("Connection lost (%v), reconnecting…\n", item.err)
+				}
+				continue
+			}
+			if t.event(item.ev) {
+				return true
+			}
+		}
+	}
+}
+
+// command runs one typed line and reports whether the player wants to quit
+func (t *terminal) command(line string) (quit bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "help", "h", "?":
+		t.printf("%s\n", helpText)
+	case "ready", "r":
+		resp, err := t.s.Ready(t.ctx)
+		if err != nil {
+			t.fail(err)
+		} else if resp.Ready {
+			t.printf("You are ready.\n")
+		} else {
+			t.printf("You are no longer ready.\n")
+		}
+	case "vote", "v":
+		if len(fields) < 2 {
+			t.printf("Usage: vote <number or name>\n")
+			return false
+		}
+		t.vote(strings.Join(fields[1:], " "))
+	case "players", "p":
+		t.refresh()
+		t.printPlayers()
+	case "role":
+		t.refresh()
+		t.printRole()
+	case "results":
+		t.printResults()
+	case "start":
+		if _, err := t.s.Start(t.ctx); err != nil {
+			t.fail(err)
+		}
+	case "restart":
+		if _, err := t.s.Restart(t.ctx); err != nil {
+			t.fail(err)
+		}
+	case "quit", "q", "exit", "leave":
+		return true
+	default:
+		t.printf("Unknown command %q, type help for the commands.\n", fields[0])
+	}
+	return false
+}
+
+// vote votes for the candidate with the given number or name
+func (t *terminal) vote(arg string) {
+	suspects := t.suspects()
+	var suspect *client.Player
+	if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(suspects) {
+		suspect = &suspects[n-1]
+	} else {
+		for i, p := range suspects {
+			if strings.EqualFold(p.Name, arg) {
+				suspect = &suspects[i]
+			}
+		}
+	}
+	if suspect == nil {
+		t.printf("No suspect %q, pick a number from the list:\n", arg)
+		t.printCandidates()
+		return
+	}
+
+	resp, err := t.s.Vote(t.ctx, suspect.ID)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	if !resp.Finished && !resp.Revote {
+		t.printf("You voted for %s.\n", suspect.Name)
+	}
+}
+
+// event handles one event of the stream and reports whether the lobby is gone
+func (t *terminal) event(ev client.Event) (closed bool) {
+	p, err := ev.Payload()
+	if err != nil {
+		t.printf("%v\n", err)
+		return false
+	}
+
+	switch p := p.(type) {
+	case client.Snapshot:
+		// Sent on every (re)connect: only repeat the phase prompt when it changed meanwhile
+		first := t.lobby == nil
+		prev := t.phase()
+		t.refresh()
+		if first {
+			t.printf("Joined lobby %s.\n", t.s.Code)
+			if p.Notice != "" {
+				t.printf("Notice: %s\n", p.Notice)
+			}
+		}
+		if first || t.phase() != prev {
+			t.enterPhase()
+		}
+	case client.PlayerJoined:
+		t.refresh()
+		if p.Rejoin {
+			t.printf("%s is back.\n", p.Name)
+		} else {
+			t.printf("%s joined.\n", p.Name)
+		}
+	case client.PlayerLeft:
+		if p.PlayerID == t.s.PlayerID {
+			t.printf("You were removed from the lobby.\n")
+			return true
+		}
+		t.refresh()
+		if p.Kicked {
+			t.printf("%s was removed from the lobby.\n", p.Name)
+		} else {
+			t.printf("%s left.\n", p.Name)
+		}
+	case client.HostChanged:
+		t.refresh()
+		if p.HostID == t.s.PlayerID {
+			t.printf("You are now the host.\n")
+		} else {
+			t.printf("%s is now the host.\n", t.name(p.HostID))
+		}
+	case client.SettingsChanged:
+		t.refresh()
+		t.printf("The host switched to %s.\n", p.Mode)
+	case client.PhaseChanged:
+		t.refresh()
+		t.enterPhase()
+	case client.ReadyChanged:
+		switch {
+		case p.PlayerID == "" || p.PlayerID == t.s.PlayerID:
+			t.printf("Ready: %d/%d\n", p.Count, p.Total)
+		case p.Ready:
+			t.printf("%s is ready (%d/%d).\n", t.name(p.PlayerID), p.Count, p.Total)
+		default:
+			t.printf("%s is no longer ready (%d/%d).\n", t.name(p.PlayerID), p.Count, p.Total)
+		}
+	case client.VoteCast:
+		if p.PlayerID == "" {
+			t.printf("Votes: %d/%d\n", p.Count, p.Total)
+		} else {
+			t.printf("%s voted (%d/%d).\n", t.name(p.PlayerID), p.Count, p.Total)
+		}
+	case client.GameFinished:
+		t.refresh()
+		t.printResults()
+		t.printWaiting()
+	case client.GameAborted:
+		t.printf("The game was aborted: %s\n", p.Reason)
+	case client.LobbyClosed:
+		switch p.Reason {
+		case "expired":
+			t.printf("The lobby expired.\n")
+		case "admin":
+			t.printf("The lobby was closed by the server operator.\n")
+		default:
+			t.printf("The host closed the lobby.\n")
+		}
+		return true
+	case client.ServerNotice:
+		if p.Message != "" {
+			t.printf("Notice: %s\n", p.Message)
+		} else {
+			t.printf("The notice was cleared.\n")
+		}
+	}
+	return false
+}
+
+// enterPhase tells the player what the current phase expects from them
+func (t *terminal) enterPhase() {
+	if t.lobby == nil {
+		return
+	}
+	switch t.lobby.Phase {
+	case client.PhaseWaiting:
+		t.printf("\n== Lobby %s ==\n", t.s.Code)
+		t.printPlayers()
+		t.printWaiting()
+	case client.PhaseReadyCheck:
+		t.printf("\n== A game of %s is starting ==\nType ready when you are ready.\n", t.lobby.Mode)
+	case client.PhaseRoleReveal:
+		t.printf("\n== Role reveal ==\n")
+		t.printRole()
+		t.printf("Type ready once you memorized it.\n")
+	case client.PhasePlaying:
+		t.printf("\n== Questions ==\n")
+		if g := t.lobby.Game; g != nil {
+			// Like the play page: a reminder of the role card and who starts
+			if r := g.You.Role; r != nil && r.Reminder != nil {
+				t.printf("%s %s\n", r.Reminder.Label, r.Reminder.Value)
+			}
+			if g.FirstQuestioner != "" {
+				t.printf("%s asks the first question.\n", g.FirstQuestioner)
+			}
+		}
+		t.printf("Type ready when you want to vote.\n")
+	case client.PhaseVoting:
+		round := 1
+		if t.lobby.Game != nil {
+			round = t.lobby.Game.VoteRound
+		}
+		t.printf("\n== Voting, round %d ==\n", round)
+		t.printCandidates()
+		t.printf("Type vote <number> to vote.\n")
+	case client.PhaseFinished:
+		t.printResults()
+		t.printWaiting()
+	}
+}
+
+// printWaiting says who starts the next game
+func (t *terminal) printWaiting() {
+	if t.lobby != nil && t.lobby.HostID == t.s.PlayerID {
+		if t.lobby.Phase == client.PhaseWaiting {
+			t.printf("You are the host: type start once at least 3 players joined.\n")
+		} else {
+			t.printf("You are the host: type restart to return to the lobby.\n")
+		}
+	} else {
+		t.printf("Waiting for the host…\n")
+	}
+}
+
+// printPlayers lists the lobby's players
+func (t *terminal) printPlayers() {
+	if t.lobby == nil {
+		return
+	}
+	for _, p := range t.lobby.Players {
+		var tags []string
+		if p.ID == t.lobby.HostID {
+			tags = append(tags, "host")
+		}
+		if p.Bot {
+			tags = append(tags, "bot")
+		}
+		if p.ID == t.s.PlayerID {
+			tags = append(tags, "you")
+		}
+		if len(tags) > 0 {
+			t.printf("  %s (%s)\n", p.Name, strings.Join(tags, ", "))
+		} else {
+			t.printf("  %s\n", p.Name)
+		}
+	}
+}
+
+// printCandidates numbers the players that can be voted for
+func (t *terminal) printCandidates() {
+	for i, p := range t.suspects() {
+		t.printf("  %d. %s\n", i+1, p.Name)
+	}
+}
+
+// suspects returns the players this player can vote for, in a stable order
+func (t *terminal) suspects() []client.Player {
+	if t.lobby == nil {
+		return nil
+	}
+	var suspects []client.Player
+	for _, p := range t.lobby.Players {
+		if p.ID != t.s.PlayerID {
+			suspects = append(suspects, p)
+		}
+	}
+	return suspects
+}
+
+// printRole shows the player's secret role card
+func (t *terminal) printRole() {
+	if t.lobby == nil || t.lobby.Game == nil || t.lobby.Game.You.Role == nil {
+		t.printf("Roles are not revealed yet.\n")
+		return
+	}
+	role := t.lobby.Game.You.Role
+	t.printf("  %s\n", role.Title)
+	for _, d := range role.Details {
+		t.printf("  %s %s\n", d.Label, d.Value)
+	}
+	if role.Hint != "" {
+		t.printf("  %s\n", role.Hint)
+	}
+}
+
+// printResults shows the outcome of the last game
+func (t *terminal) printResults() {
+	results, err := t.s.Results(t.ctx)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+
+	g := results.Game
+	t.printf("\n== Game %d over ==\n", g.Number)
+	innocents, impostor := "Innocents", "Spy"
+	if g.Mode == client.ModeUndercover {
+		innocents, impostor = "Civilians", "Undercover"
+	}
+	if g.InnocentsWon {
+		t.printf("%s win!\n", innocents)
+	} else {
+		t.printf("%s wins!\n", impostor)
+	}
+	switch {
+	case g.SpyForfeited:
+		t.printf("The %s left the game.\n", strings.ToLower(impostor))
+	case g.Tie:
+		t.printf("The vote ended in a tie.\n")
+	}
+	if g.VotedOut != "" {
+		for _, p := range g.Players {
+			if p.PlayerID == g.VotedOut {
+				t.printf("Voted out: %s\n", p.Name)
+			}
+		}
+	}
+	for _, s := range g.Secrets {
+		t.printf("%s: %s\n", s.Label, s.Value)
+	}
+	for _, p := range g.Players {
+		outcome := "lost"
+		if p.Won {
+			outcome = "won"
+		}
+		t.printf("  %-16s %-20s %s (%+.0f)\n", p.Name, p.Role, outcome, p.RatingChange)
+	}
+}
+
+// refresh fetches the current lobby state
+func (t *terminal) refresh() {
+	lobby, err := t.s.Lobby(t.ctx)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.lobby = lobby
+}
+
+// phase returns the last known phase
+func (t *terminal) phase() string {
+	if t.lobby == nil {
+		return ""
+	}
+	return t.lobby.Phase
+}
+
+// name returns the display name of a player
+func (t *terminal) name(playerID string) string {
+	if playerID == t.s.PlayerID {
+		return "You"
+	}
+	if t.lobby != nil {
+		for _, p := range t.lobby.Players {
+			if p.ID == playerID {
+				return p.Name
+			}
+		}
+	}
+	return "Someone"
+}
+
+// fail prints the message of a failed request
+func (t *terminal) fail(err error) {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		t.printf("%s\n", apiErr.Message)
+		return
+	}
+	t.printf("Error: %v\n", err)
+}
+
+func (t *terminal) printf(format string, args ...any) {
+	fmt.Fprintf(t.out, format, args...)
+}
@@ -0,0 +1,141 @@
+// Package api defines the JSON documents of the /api/v1 REST API
+// The handlers live in internal/handlers; openapi.yaml describes the same documents.
+package api
+
+import (
+	_ "embed"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/export"
+)
+
+// Version is the path prefix of this API
+const Version = "/api/v1"
+
+// OpenAPI is the OpenAPI 3 document of the API, served at /api/v1/openapi.yaml
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// Error codes of ErrorBody.Code; clients should branch on these rather than on messages
+const (
+	CodeBadRequest       = "bad_request"        // malformed body or missing field
+	CodeUnauthorized     = "unauthorized"       // no valid token or player_id cookie
+	CodeForbidden        = "forbidden"          // e.g. only the host can start the game
+	CodeNotFound         = "not_found"          // unknown lobby or route
+	CodeMethodNotAllowed = "method_not_allowed" // wrong HTTP method for the route
+	CodeNameTaken        = "name_taken"         // another player in the lobby has that name
+	CodeGameInProgress   = "game_in_progress"   // the action needs the lobby to be waiting
+	CodeWrongPhase       = "wrong_phase"        // the action does not fit the current game phase
+	CodeUnavailable      = "unavailable"        // the server is shutting down
+)
+
+// Error is the body of every non-2xx response
+type Error struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes what went wrong
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"` // human-readable, may change between versions
+}
+
+// CreateLobbyRequest is the body of POST /lobbies
+type CreateLobbyRequest struct {
+	Name string `json:"name"` // the host's display name
+}
+
+// JoinLobbyRequest is the body of POST /lobbies/{code}/join
+type JoinLobbyRequest struct {
+	Name string `json:"name"`
+}
+
+// LeaveLobbyRequest is the optional body of POST /lobbies/{code}/leave
+type LeaveLobbyRequest struct {
+	NewHostID string `json:"new_host_id,omitempty"` // successor when the host leaves; picked by the server when empty
+}
+
+// VoteRequest is the body of POST /lobbies/{code}/vote
+type VoteRequest struct {
+	Suspect string `json:"suspect"` // player ID voted for
+}
+
+// Session is returned when a lobby is created or joined
+// Send Token as "Authorization: Bearer <token>" on every later request for this lobby
+type Session struct {
+	Token    string `json:"token"`
+	PlayerID string `json:"player_id"`
+	Lobby    Lobby  `json:"lobby"`
+}
+
+// Lobby is the state of a lobby as seen by one player
+type Lobby struct {
+	Code    string          `json:"code"`
+	Phase   string          `json:"phase"` // "waiting" or a game phase
+	HostID  string          `json:"host_id"`
+	Mode    string          `json:"mode"`
+	Options map[string]bool `json:"options"`
+	Players []Player        `json:"players"`
+	Scores  []export.Score  `json:"scores"`
+	Game    *Game           `json:"game,omitempty"` // nil while waiting
+}
+
+// Player is a member of a lobby
+type Player struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Bot  bool   `json:"bot,omitempty"`
+}
+
+// Game is the game in progress as seen by one player
+type Game struct {
+	Phase           string `json:"phase"`
+	VoteRound       int    `json:"vote_round"`
+	Ready           int    `json:"ready"` // ready players of the ready check, role reveal or playing phase
+	Votes           int    `json:"votes"` // votes cast in the current round
+	Total           int    `json:"total"`
+	FirstQuestioner string `json:"first_questioner,omitempty"` // player name
+	You             You    `json:"you"`
+}
+
+// You is the requesting player's part of a game
+type You struct {
+	Ready bool  `json:"ready"`
+	Voted bool  `json:"voted"`
+	Role  *Role `json:"role,omitempty"` // nil before the role reveal
+}
+
+// Role is the secret a player sees on their role card
+type Role struct {
+	Title    string   `json:"title"`
+	Spy      bool     `json:"spy"` // on the hidden team
+	Details  []Detail `json:"details"`
+	Hint     string   `json:"hint,omitempty"`
+	Reminder *Detail  `json:"reminder,omitempty"`
+}
+
+// Detail is a labelled secret, e.g. the location
+type Detail struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// ReadyResponse is returned by POST /lobbies/{code}/ready
+type ReadyResponse struct {
+	Phase     string `json:"phase"`                // the phase the readiness applied to
+	Ready     bool   `json:"ready"`                // the player's readiness after the toggle
+	NextPhase string `json:"next_phase,omitempty"` // set when everyone was ready and the game moved on
+}
+
+// VoteResponse is returned by POST /lobbies/{code}/vote
+type VoteResponse struct {
+	Round    int  `json:"round"`    // the round the vote was cast in
+	Revote   bool `json:"revote"`   // the round ended in a tie and a new one started
+	Finished bool `json:"finished"` // the vote decided the game
+}
+
+// Results is returned by GET /lobbies/{code}/results once the game is finished
+type Results struct {
+	Game   export.Game    `json:"game"`
+	Scores []export.Score `json:"scores"`
+}
@@ -0,0 +1,164 @@
+// Package events defines the typed changes a lobby goes through
+// The game logic publishes them; each transport renders them in its own format
+// (HTMX partials for browser pages, JSON for API clients).
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/render"
+)
+
+// Event is a change to a lobby
+type Event interface {
+	// Type is the stable name of the event in JSON, e.g. "player_joined"
+	Type() string
+}
+
+// PlayerJoined is sent when a player or bot joins (or rejoins) the lobby
+type PlayerJoined struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Bot      bool   `json:"bot,omitempty"`
+	Rejoin   bool   `json:"rejoin,omitempty"` // the player was already in the lobby
+}
+
+// PlayerLeft is sent when a player leaves, disconnects for good or a bot is removed
+type PlayerLeft struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Bot      bool   `json:"bot,omitempty"`
+	Kicked   bool   `json:"kicked,omitempty"` // removed by an operator
+}
+
+// HostChanged is sent when the lobby gets a new host
+type HostChanged struct {
+	HostID    string `json:"host_id"`
+	Automatic bool   `json:"automatic"` // picked by the server rather than by the previous host
+}
+
+// SettingsChanged is sent when the host picks another mode or mode options
+type SettingsChanged struct {
+	Mode    models.GameMode `json:"mode"`
+	Options map[string]bool `json:"options,omitempty"`
+}
+
+// PhaseChanged is sent when a game starts, moves to its next phase, starts a revote
+// or the lobby returns to the waiting room
+type PhaseChanged struct {
+	Phase     models.GameStatus `json:"phase"`
+	VoteRound int               `json:"vote_round,omitempty"` // set for the voting phase
+}
+
+// ReadyChanged is sent when the number of ready players of a phase changes
+type ReadyChanged struct {
+	Phase    models.GameStatus `json:"phase"`
+	PlayerID string            `json:"player_id,omitempty"` // empty when a leaving player changed the count
+	Ready    bool              `json:"ready"`
+	Count    int               `json:"count"`
+	Total    int               `json:"total"`
+}
+
+// VoteCast is sent when the number of votes in the current round changes
+// Ballots stay secret until the game is finished
+type VoteCast struct {
+	Round    int    `json:"round"`
+	PlayerID string `json:"player_id,omitempty"` // empty when a leaving player changed the count
+	Count    int    `json:"count"`
+	Total    int    `json:"total"`
+}
+
+// GameFinished is sent when the game has a result
+type GameFinished struct {
+	InnocentWon  bool   `json:"innocent_won"`
+	SpyForfeited bool   `json:"spy_forfeited,omitempty"`
+	MostVoted    string `json:"most_voted,omitempty"` // empty on a tie or forfeit
+	IsTie        bool   `json:"is_tie,omitempty"`
+}
+
+// GameAborted is sent when a game ends without a result; the lobby returns to the waiting room shortly after
+type GameAborted struct {
+	Reason string `json:"reason"`
+}
+
+// LobbyClosed is sent when the host closes the lobby or it expires
+type LobbyClosed struct {
+	Reason string `json:"reason"` // "closed", "expired" or "admin"
+}
+
+// ServerNotice is sent to every lobby when an operator sets or clears the maintenance message
+type ServerNotice struct {
+	Message string `json:"message"` // empty when the notice was cleared
+}
+
+// Snapshot is the current state of the lobby, sent to every client when it connects
+type Snapshot struct {
+	Phase     models.GameStatus `json:"phase"`
+	HostID    string            `json:"host_id"`
+	Mode      models.GameMode   `json:"mode"`
+	Players   []SnapshotPlayer  `json:"players"`
+	VoteRound int               `json:"vote_round,omitempty"`
+	Ready     int               `json:"ready"`            // ready players (ready check, role reveal and playing)
+	Votes     int               `json:"votes"`            // votes cast (voting)
+	Notice    string            `json:"notice,omitempty"` // the current maintenance message
+}
+
+// SnapshotPlayer is a player of a Snapshot
+type SnapshotPlayer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Bot  bool   `json:"bot,omitempty"`
+}
+
+func (PlayerJoined) Type() string    { return "player_joined" }
+func (PlayerLeft) Type() string      { return "player_left" }
+func (HostChanged) Type() string     { return "host_changed" }
+func (SettingsChanged) Type() string { return "settings_changed" }
+func (PhaseChanged) Type() string    { return "phase_changed" }
+func (ReadyChanged) Type() string    { return "ready_changed" }
+func (VoteCast) Type() string        { return "vote_cast" }
+func (GameFinished) Type() string    { return "game_finished" }
+func (GameAborted) Type() string     { return "game_aborted" }
+func (LobbyClosed) Type() string     { return "lobby_closed" }
+func (ServerNotice) Type() string    { return "server_notice" }
+func (Snapshot) Type() string        { return "snapshot" }
+
+// Envelope is the JSON form of an event
+type Envelope struct {
+	Type  string    `json:"type"`
+	Lobby string    `json:"lobby"`
+	Time  time.Time `json:"time"`
+	Data  Event     `json:"data"`
+}
+
+// Marshal encodes an event of a lobby as an Envelope
+func Marshal(lobbyCode string, ev Event, at time.Time) ([]byte, error) {
+	return json.Marshal(Envelope{Type: ev.Type(), Lobby: lobbyCode, Time: at.UTC(), Data: ev})
+}
+
+// NewSnapshot builds the snapshot of a lobby (caller must hold the lobby lock)
+func NewSnapshot(lobby *models.Lobby) Snapshot {
+	s := Snapshot{Phase: models.StatusWaiting, HostID: lobby.Host, Mode: lobby.Mode}
+	for _, p := range render.GetPlayerList(lobby.Players) {
+		s.Players = append(s.Players, SnapshotPlayer{ID: p.ID, Name: p.Name, Bot: p.IsBot})
+	}
+	if g := lobby.CurrentGame; g != nil {
+		s.Phase = g.Status
+		s.Mode = g.Mode
+		switch g.Status {
+		case models.StatusReadyCheck:
+			s.Ready = game.CountReadyPlayers(g.ReadyToReveal, lobby.Players)
+		case models.StatusRoleReveal:
+			s.Ready = game.CountReadyPlayers(g.ReadyAfterReveal, lobby.Players)
+		case models.StatusPlaying:
+			s.Ready = game.CountReadyPlayers(g.ReadyToVote, lobby.Players)
+		case models.StatusVoting:
+			s.VoteRound = g.VoteRound
+			s.Votes = len(g.Votes)
+		}
+	}
+	return s
+}
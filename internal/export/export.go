@@ -0,0 +1,275 @@
+// Package export converts a lobby into the documented download formats (JSON and CSV)
+// The JSON types are the stable schema: fields are only ever added, and any
+// incompatible change bumps SchemaVersion.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/spyfall"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/undercover"
+)
+
+// SchemaVersion is the version of the JSON export format
+const SchemaVersion = 1
+
+// Lobby is the root of a JSON export
+type Lobby struct {
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Code          string    `json:"code"`
+	Scores        []Score   `json:"scores"`
+	Games         []Game    `json:"games"`
+}
+
+// Score is a player's standing in the lobby
+type Score struct {
+	PlayerID       string   `json:"player_id"`
+	Name           string   `json:"name"`
+	Bot            bool     `json:"bot"`
+	Wins           int      `json:"wins"`
+	Losses         int      `json:"losses"`
+	SpyRating      *float64 `json:"spy_rating"` // null before the first spy game
+	SpyGames       int      `json:"spy_games"`
+	InnocentRating *float64 `json:"innocent_rating"` // null before the first innocent game
+	InnocentGames  int      `json:"innocent_games"`
+}
+
+// Game is one finished game
+type Game struct {
+	Number         int        `json:"number"`
+	Mode           string     `json:"mode"` // "spyfall" or "undercover"
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     time.Time  `json:"finished_at"`
+	Secrets        []Secret   `json:"secrets"`
+	Location       string     `json:"location,omitempty"`        // Spyfall only, also in Secrets
+	CivilianWord   string     `json:"civilian_word,omitempty"`   // Undercover only, also in Secrets
+	UndercoverWord string     `json:"undercover_word,omitempty"` // Undercover only, also in Secrets
+	InnocentsWon   bool       `json:"innocents_won"`
+	Tie            bool       `json:"tie"`
+	SpyForfeited   bool       `json:"spy_forfeited"`
+	VotedOut       string     `json:"voted_out,omitempty"` // player_id
+	Players        []Player   `json:"players"`
+	VoteRounds     [][]Ballot `json:"vote_rounds"` // tie-break rounds first, the deciding round last
+}
+
+// Secret is a labelled secret the game was played with, e.g. the location
+type Secret struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Player is a participant of a finished game
+type Player struct {
+	PlayerID     string  `json:"player_id"`
+	Name         string  `json:"name"`
+	Role         string  `json:"role"`
+	Impostor     bool    `json:"impostor"`
+	Left         bool    `json:"left"` // forfeiting spy
+	Won          bool    `json:"won"`
+	RatingChange float64 `json:"rating_change"`
+}
+
+// Ballot is one vote in a voting round
+type Ballot struct {
+	Voter   string `json:"voter"`   // player_id
+	Suspect string `json:"suspect"` // player_id
+}
+
+// FromLobby builds the export of a lobby (caller must hold the lobby lock)
+func FromLobby(lobby *models.Lobby, now time.Time) *Lobby {
+	out := &Lobby{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    now.UTC(),
+		Code:          lobby.Code,
+		Scores:        Scores(lobby),
+		Games:         make([]Game, 0, len(lobby.History)),
+	}
+	for _, rec := range lobby.History {
+		out.Games = append(out.Games, FromRecord(rec))
+	}
+	return out
+}
+
+// Scores returns the lobby scores, most wins first (caller must hold the lobby lock)
+func Scores(lobby *models.Lobby) []Score {
+	scores := make([]Score, 0, len(lobby.Scores))
+	for id, score := range lobby.Scores {
+		s := Score{
+			PlayerID:       id,
+			Wins:           score.GamesWon,
+			Losses:         score.GamesLost,
+			SpyRating:      ratingValue(score.Rating.Spy),
+			SpyGames:       score.Rating.Spy.Games,
+			InnocentRating: ratingValue(score.Rating.Innocent),
+			InnocentGames:  score.Rating.Innocent.Games,
+		}
+		if p, ok := lobby.Players[id]; ok {
+			s.Name = p.Name
+			s.Bot = p.IsBot
+		}
+		scores = append(scores, s)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return scores
+}
+
+// FromRecord converts an archived game
+func FromRecord(rec *models.GameRecord) Game {
+	g := Game{
+		Number:       rec.Number,
+		Mode:         string(rec.Mode),
+		FinishedAt:   rec.FinishedAt.UTC(),
+		Secrets:      make([]Secret, 0, len(rec.Secrets)),
+		InnocentsWon: rec.Result.InnocentWon,
+		Tie:          rec.Result.IsTie,
+		SpyForfeited: rec.Result.SpyForfeited,
+		VotedOut:     rec.Result.MostVoted,
+		Players:      make([]Player, 0, len(rec.Players)),
+		VoteRounds:   make([][]Ballot, 0, len(rec.VoteRounds)),
+	}
+	for _, s := range rec.Secrets {
+		g.Secrets = append(g.Secrets, Secret{Key: s.Key, Label: s.Label, Value: s.Value})
+		switch {
+		case rec.Mode == spyfall.ID && s.Key == spyfall.SecretLocation:
+			g.Location = s.Value
+		case rec.Mode == undercover.ID && s.Key == undercover.SecretCivilianWord:
+			g.CivilianWord = s.Value
+		case rec.Mode == undercover.ID && s.Key == undercover.SecretUndercoverWord:
+			g.UndercoverWord = s.Value
+		}
+	}
+	if started, ok := rec.PhaseStartedAt[models.StatusReadyCheck]; ok {
+		started = started.UTC()
+		g.StartedAt = &started
+	}
+
+	for _, p := range rec.Players {
+		g.Players = append(g.Players, Player{
+			PlayerID:     p.ID,
+			Name:         p.Name,
+			Role:         p.Role,
+			Impostor:     p.Impostor,
+			Left:         p.Left,
+			Won:          p.Impostor != rec.Result.InnocentWon,
+			RatingChange: rec.Result.RatingDeltas[p.ID],
+		})
+	}
+
+	for _, round := range rec.VoteRounds {
+		ballots := make([]Ballot, 0, len(round))
+		for voter, suspect := range round {
+			ballots = append(ballots, Ballot{Voter: voter, Suspect: suspect})
+		}
+		sort.Slice(ballots, func(i, j int) bool { return ballots[i].Voter < ballots[j].Voter })
+		g.VoteRounds = append(g.VoteRounds, ballots)
+	}
+	return g
+}
+
+// ratingValue returns the rating, or nil before the first game
+func ratingValue(r models.RoleRating) *float64 {
+	if r.Games == 0 {
+		return nil
+	}
+	v := r.Value
+	return &v
+}
+
+// WriteScoresCSV writes one row per player with the columns of Score
+func WriteScoresCSV(w io.Writer, lobby *Lobby) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"player_id", "name", "bot", "wins", "losses", "spy_rating", "spy_games", "innocent_rating", "innocent_games"})
+	for _, s := range lobby.Scores {
+		cw.Write([]string{
+			csvText(s.PlayerID),
+			csvText(s.Name),
+			strconv.FormatBool(s.Bot),
+			strconv.Itoa(s.Wins),
+			strconv.Itoa(s.Losses),
+			formatRating(s.SpyRating),
+			strconv.Itoa(s.SpyGames),
+			formatRating(s.InnocentRating),
+			strconv.Itoa(s.InnocentGames),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteGamesCSV writes one row per player per finished game
+// Game-level columns repeat on every row of the game so the file can be filtered in a spreadsheet
+func WriteGamesCSV(w io.Writer, lobby *Lobby) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"game", "mode", "started_at", "finished_at", "location", "civilian_word", "undercover_word",
+		"innocents_won", "tie", "spy_forfeited", "player_id", "name", "role", "impostor", "left", "won", "rating_change", "voted_for",
+	})
+	for _, g := range lobby.Games {
+		started := ""
+		if g.StartedAt != nil {
+			started = g.StartedAt.Format(time.RFC3339)
+		}
+		// Ballots of the deciding round
+		votedFor := make(map[string]string)
+		if n := len(g.VoteRounds); n > 0 {
+			for _, b := range g.VoteRounds[n-1] {
+				votedFor[b.Voter] = b.Suspect
+			}
+		}
+		for _, p := range g.Players {
+			cw.Write([]string{
+				strconv.Itoa(g.Number),
+				g.Mode,
+				started,
+				g.FinishedAt.Format(time.RFC3339),
+				csvText(g.Location),
+				csvText(g.CivilianWord),
+				csvText(g.UndercoverWord),
+				strconv.FormatBool(g.InnocentsWon),
+				strconv.FormatBool(g.Tie),
+				strconv.FormatBool(g.SpyForfeited),
+				csvText(p.PlayerID),
+				csvText(p.Name),
+				csvText(p.Role),
+				strconv.FormatBool(p.Impostor),
+				strconv.FormatBool(p.Left),
+				strconv.FormatBool(p.Won),
+				strconv.FormatFloat(p.RatingChange, 'f', 1, 64),
+				csvText(votedFor[p.PlayerID]),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText guards a text cell that comes from players or word lists (names, IDs, words) against formula injection
+// Spreadsheets run cells starting with =, +, -, @, tab or CR as formulas; a leading ' makes them plain text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// formatRating formats an optional rating for CSV (empty before the first game)
+func formatRating(r *float64) string {
+	if r == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*r, 'f', 1, 64)
+}
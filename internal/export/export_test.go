@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Alice", "Alice"},
+		{"", ""},
+		{"=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"Mr=White", "Mr=White"}, // only the first character counts
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteGamesCSVHasSecrets(t *testing.T) {
+	lobby := &Lobby{Games: []Game{
+		{
+			Number: 1, Mode: "spyfall", Location: "Beach", InnocentsWon: true,
+			Players: []Player{{PlayerID: "p1", Name: "Alice", Role: "Innocent", Won: true}},
+		},
+		{
+			Number: 2, Mode: "undercover", CivilianWord: "cat", UndercoverWord: "=dog",
+			Players: []Player{{PlayerID: "p1", Name: "Alice", Role: "Civilian"}},
+		},
+	}}
+	for i := range lobby.Games {
+		lobby.Games[i].FinishedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var b strings.Builder
+	if err := WriteGamesCSV(&b, lobby); err != nil {
+		t.Fatalf("WriteGamesCSV: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV back: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want a header and one row per game", len(rows))
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, c := range []struct {
+		row  int
+		name string
+		want string
+	}{
+		{1, "location", "Beach"},
+		{1, "civilian_word", ""},
+		{2, "location", ""},
+		{2, "civilian_word", "cat"},
+		{2, "undercover_word", "'=dog"},
+	} {
+		i, ok := col[c.name]
+		if !ok {
+			t.Fatalf("no %s column in %v", c.name, rows[0])
+		}
+		if got := rows[c.row][i]; got != c.want {
+			t.Errorf("row %d, %s: got %q, want %q", c.row, c.name, got, c.want)
+		}
+	}
+}
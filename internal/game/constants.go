@@ -13,11 +13,31 @@ const (
 	// ReadyThresholdMajority requires >50% of players to be ready (phase 3)
 	ReadyThresholdMajority = 0.5
 
-	// SSEBufferSize is the buffer size for SSE message channels
+	// MaxHistory is the number of finished games kept per lobby
+	MaxHistory = 50
+
+	// RatingK is the largest rating change a single game can cause
+	RatingK = 32
+
+	// MaxBots is the maximum number of bot players per lobby
+	MaxBots = 8
+
+	// BotMinDelayMillis and BotMaxDelayMillis bound how long a bot waits before acting
+	BotMinDelayMillis = 1500
+	BotMaxDelayMillis = 4000
+
+	// SSEBufferSize is the number of messages queued per SSE client before the overflow policy applies
 	SSEBufferSize = 10
 
-	// SSETimeout is the timeout for sending messages to SSE clients
-	SSETimeoutSeconds = 1
+	// SSEReplayBuffer is the number of recent messages per lobby kept for Last-Event-ID replay
+	// (every event is kept once per format, HTML and JSON)
+	SSEReplayBuffer = 128
+
+	// SSEHeartbeatSeconds is how often an idle SSE stream gets a keep-alive comment
+	SSEHeartbeatSeconds = 15
+
+	// SSEWriteTimeoutSeconds bounds each write to an SSE client; slower clients are dropped
+	SSEWriteTimeoutSeconds = 10
 
 	// RoomCodeLength is the length of generated room codes
 	RoomCodeLength = 6
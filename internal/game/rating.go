@@ -0,0 +1,57 @@
+package game
+
+import (
+	"math"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// RatedPlayer is a participant of a finished game as seen by the rating system
+type RatedPlayer struct {
+	ID       string
+	Impostor bool
+	Rating   models.Rating
+}
+
+// RateGame returns each player's rating change for a finished game
+// Every player is rated on the side they played against the average rating of the other team,
+// so beating a strong team is worth more than beating a weak one. Spy and innocent ratings
+// never mix: a spy's spy rating is compared with the innocents' innocent ratings and vice versa.
+func RateGame(players []RatedPlayer, innocentWon bool) map[string]float64 {
+	var spySum, innocentSum float64
+	var spies, innocents int
+	for _, p := range players {
+		if p.Impostor {
+			spySum += p.Rating.Spy.Current()
+			spies++
+		} else {
+			innocentSum += p.Rating.Innocent.Current()
+			innocents++
+		}
+	}
+	deltas := make(map[string]float64, len(players))
+	if spies == 0 || innocents == 0 {
+		return deltas
+	}
+	spyAvg := spySum / float64(spies)
+	innocentAvg := innocentSum / float64(innocents)
+
+	for _, p := range players {
+		own := p.Rating.For(p.Impostor).Current()
+		opponents, won := spyAvg, innocentWon
+		if p.Impostor {
+			opponents, won = innocentAvg, !innocentWon
+		}
+		score := 0.0
+		if won {
+			score = 1
+		}
+		deltas[p.ID] = RatingK * (score - expectedScore(own, opponents))
+	}
+	return deltas
+}
+
+// expectedScore is the Elo win probability of a player rated own against opponents
+func expectedScore(own, opponents float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponents-own)/400))
+}
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/spyfall"
+)
+
+// rated returns a player with one game played on each side
+func rated(id string, impostor bool, spy, innocent float64) RatedPlayer {
+	return RatedPlayer{ID: id, Impostor: impostor, Rating: models.Rating{
+		Spy:      models.RoleRating{Value: spy, Games: 1},
+		Innocent: models.RoleRating{Value: innocent, Games: 1},
+	}}
+}
+
+func TestRateGame(t *testing.T) {
+	tests := []struct {
+		name        string
+		players     []RatedPlayer
+		innocentWon bool
+		want        map[string]float64
+	}{
+		{
+			name:        "new players, innocents win",
+			players:     []RatedPlayer{{ID: "a"}, {ID: "b"}, {ID: "spy", Impostor: true}},
+			innocentWon: true,
+			want:        map[string]float64{"a": 16, "b": 16, "spy": -16},
+		},
+		{
+			// Everyone is rated against the other team's average on the side they played;
+			// the ratings of the other side (2000 and 0 here) must not count
+			name: "three innocents against two impostors, impostors win",
+			players: []RatedPlayer{
+				rated("a", false, 2000, 1200),
+				rated("b", false, 2000, 1000),
+				rated("c", false, 2000, 800),
+				rated("uc", true, 1100, 0),
+				rated("mw", true, 900, 0),
+			},
+			innocentWon: false,
+			want:        map[string]float64{"a": -24.31, "b": -16, "c": -7.69, "uc": 11.52, "mw": 20.48},
+		},
+		{
+			name:        "favourite spy beats a weaker team",
+			players:     []RatedPlayer{rated("a", false, 0, 900), rated("b", false, 0, 900), rated("spy", true, 1100, 0)},
+			innocentWon: false,
+			want:        map[string]float64{"a": -7.69, "b": -7.69, "spy": 7.69},
+		},
+		{
+			name:        "no impostor left to play against",
+			players:     []RatedPlayer{{ID: "a"}, {ID: "b"}},
+			innocentWon: true,
+			want:        map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RateGame(tt.players, tt.innocentWon)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rating changes, want %d: %v", len(got), len(tt.want), got)
+			}
+			for id, want := range tt.want {
+				if math.Abs(got[id]-want) > 0.01 {
+					t.Errorf("rating change of %s: got %.2f, want %.2f", id, got[id], want)
+				}
+			}
+		})
+	}
+}
+
+// A spy who left is no longer a lobby player but is still rated as the losing impostor
+func TestComputeResultRatesForfeitingSpy(t *testing.T) {
+	g := &models.Game{SpyID: "spy", SpyForfeited: true, Votes: map[string]string{}}
+	players, scores := newTestPlayers("a", "b")
+	result := ComputeResult(g, spyfall.New(nil, nil), players, scores)
+
+	want := map[string]float64{"a": 16, "b": 16, "spy": -16}
+	if len(result.RatingDeltas) != len(want) {
+		t.Fatalf("got rating changes %v, want %v", result.RatingDeltas, want)
+	}
+	for id, w := range want {
+		if math.Abs(result.RatingDeltas[id]-w) > 0.01 {
+			t.Errorf("rating change of %s: got %.2f, want %.2f", id, result.RatingDeltas[id], w)
+		}
+	}
+}
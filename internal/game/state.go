@@ -1,51 +1,98 @@
 package game
 
 import (
+	"sort"
+
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
 )
 
-// VoteResult represents the outcome of vote counting
-type VoteResult struct {
-	MostVoted      string
-	IsTie          bool
-	InnocentWon    bool
-	VoteCount      map[string]int
-	VotedCorrectly map[string]bool
-}
-
-// CountVotes analyzes votes and determines the result
-func CountVotes(game *models.Game, players map[string]*models.Player) *VoteResult {
+// TallyVotes counts ballots and returns the players with the most votes, sorted by ID
+// More than one leader means the round is tied
+func TallyVotes(votes map[string]string) (map[string]int, []string) {
 	voteCount := make(map[string]int)
-	for _, votedFor := range game.Votes {
+	for _, votedFor := range votes {
 		voteCount[votedFor]++
 	}
 
 	maxVotes := 0
-	var playersWithMaxVotes []string
+	var leaders []string
 	for pID, count := range voteCount {
 		if count > maxVotes {
 			maxVotes = count
-			playersWithMaxVotes = []string{pID}
+			leaders = []string{pID}
 		} else if count == maxVotes {
-			playersWithMaxVotes = append(playersWithMaxVotes, pID)
+			leaders = append(leaders, pID)
 		}
 	}
+	sort.Strings(leaders)
+	return voteCount, leaders
+}
 
-	result := &VoteResult{
-		VoteCount: voteCount,
-		IsTie:     len(playersWithMaxVotes) > 1,
+// CloseVoteRound ends a voting round everyone took part in and reports whether the game is decided
+// A tie before MaxVoteRounds starts a revote and keeps the round's ballots in VoteHistory;
+// the last round decides the game even when it is tied
+func CloseVoteRound(g *models.Game) bool {
+	_, leaders := TallyVotes(g.Votes)
+	if len(leaders) <= 1 || g.VoteRound >= MaxVoteRounds {
+		return true
 	}
+	g.VoteHistory = append(g.VoteHistory, g.Votes)
+	g.Votes = make(map[string]string)
+	g.VoteRound++
+	return false
+}
 
-	if !result.IsTie {
-		result.MostVoted = playersWithMaxVotes[0]
-		result.InnocentWon = result.MostVoted == game.SpyID
+// ComputeResult determines the outcome of a game that is about to finish using the mode rules
+// players are the lobby members who get scored (a forfeiting spy has already left); scores hold their current ratings
+func ComputeResult(g *models.Game, mode modes.Mode, players map[string]*models.Player, scores map[string]*models.PlayerScore) *models.GameResult {
+	voteCount, leaders := TallyVotes(g.Votes)
+	result := &models.GameResult{
+		VoteCount:      voteCount,
+		SpyForfeited:   g.SpyForfeited,
+		VotedCorrectly: make(map[string]bool),
+		ScoreDeltas:    make(map[string]models.PlayerScore),
 	}
 
-	// Build voted correctly map
-	result.VotedCorrectly = make(map[string]bool)
-	for voterID, suspectID := range game.Votes {
-		result.VotedCorrectly[voterID] = suspectID == game.SpyID
+	if g.SpyForfeited {
+		// Spy left - innocents win by default, the ballots so far don't count
+		result.InnocentWon = true
+	} else {
+		result.IsTie = len(leaders) > 1
+		if len(leaders) == 1 {
+			result.MostVoted = leaders[0]
+		}
+		result.InnocentWon = mode.InnocentsWin(g, result.MostVoted)
+	}
+
+	for voterID, suspectID := range g.Votes {
+		result.VotedCorrectly[voterID] = mode.IsImpostor(g, suspectID)
+	}
+
+	// Impostors win when the innocents lose and vice versa
+	for id := range players {
+		var delta models.PlayerScore
+		if mode.IsImpostor(g, id) == result.InnocentWon {
+			delta.GamesLost = 1
+		} else {
+			delta.GamesWon = 1
+		}
+		result.ScoreDeltas[id] = delta
+	}
+
+	// A forfeiting spy still counts towards the strength of the team the innocents beat
+	rated := make([]RatedPlayer, 0, len(players)+1)
+	for id := range players {
+		rp := RatedPlayer{ID: id, Impostor: mode.IsImpostor(g, id)}
+		if score, ok := scores[id]; ok {
+			rp.Rating = score.Rating
+		}
+		rated = append(rated, rp)
+	}
+	if g.SpyForfeited {
+		rated = append(rated, RatedPlayer{ID: g.SpyID, Impostor: true})
 	}
+	result.RatingDeltas = RateGame(rated, result.InnocentWon)
 
 	return result
 }
@@ -0,0 +1,246 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/spyfall"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/undercover"
+)
+
+// newTestPlayers returns lobby players and fresh scores for the given IDs
+func newTestPlayers(ids ...string) (map[string]*models.Player, map[string]*models.PlayerScore) {
+	players := make(map[string]*models.Player, len(ids))
+	scores := make(map[string]*models.PlayerScore, len(ids))
+	for _, id := range ids {
+		players[id] = &models.Player{ID: id, Name: id}
+		scores[id] = &models.PlayerScore{}
+	}
+	return players, scores
+}
+
+func TestTallyVotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		votes   map[string]string
+		count   map[string]int
+		leaders []string
+	}{
+		{
+			name:    "clear leader",
+			votes:   map[string]string{"a": "c", "b": "c", "c": "a"},
+			count:   map[string]int{"c": 2, "a": 1},
+			leaders: []string{"c"},
+		},
+		{
+			name:    "tie",
+			votes:   map[string]string{"a": "d", "b": "c", "c": "b", "d": "a"},
+			count:   map[string]int{"a": 1, "b": 1, "c": 1, "d": 1},
+			leaders: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:    "tie between two of three suspects",
+			votes:   map[string]string{"a": "e", "b": "e", "c": "b", "d": "b", "e": "a"},
+			count:   map[string]int{"e": 2, "b": 2, "a": 1},
+			leaders: []string{"b", "e"},
+		},
+		{
+			name:  "no ballots",
+			votes: map[string]string{},
+			count: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order changes between runs; the leaders must not
+			for range 20 {
+				count, leaders := TallyVotes(tt.votes)
+				if !reflect.DeepEqual(count, tt.count) {
+					t.Fatalf("got count %v, want %v", count, tt.count)
+				}
+				if !reflect.DeepEqual(leaders, tt.leaders) {
+					t.Fatalf("got leaders %v, want %v", leaders, tt.leaders)
+				}
+			}
+		})
+	}
+}
+
+func TestCloseVoteRound(t *testing.T) {
+	tied := map[string]string{"a": "b", "b": "spy", "spy": "a"}
+	tests := []struct {
+		name    string
+		rounds  []map[string]string // ballots of each round, in order
+		decided int                 // round that decides the game
+	}{
+		{
+			name:    "clear vote in the first round",
+			rounds:  []map[string]string{{"a": "spy", "b": "spy", "spy": "a"}},
+			decided: 1,
+		},
+		{
+			name:    "tie broken in the second round",
+			rounds:  []map[string]string{tied, {"a": "spy", "b": "spy", "spy": "a"}},
+			decided: 2,
+		},
+		{
+			name:    "tie in every round",
+			rounds:  []map[string]string{tied, tied, tied},
+			decided: MaxVoteRounds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &models.Game{SpyID: "spy", VoteRound: 1}
+			for i, ballots := range tt.rounds {
+				g.Votes = ballots
+				finished := CloseVoteRound(g)
+				if last := i == len(tt.rounds)-1; finished != last {
+					t.Fatalf("round %d: got finished %v, want %v", i+1, finished, last)
+				}
+			}
+			if g.VoteRound != tt.decided {
+				t.Errorf("decided in round %d, want %d", g.VoteRound, tt.decided)
+			}
+			// Every tied round is kept in order, the deciding one stays in Votes
+			tiedRounds := tt.rounds[:len(tt.rounds)-1]
+			if len(g.VoteHistory) != len(tiedRounds) {
+				t.Fatalf("got %d rounds in the vote history, want %d", len(g.VoteHistory), len(tiedRounds))
+			}
+			for i := range tiedRounds {
+				if !reflect.DeepEqual(g.VoteHistory[i], tiedRounds[i]) {
+					t.Errorf("vote history round %d: got %v, want %v", i+1, g.VoteHistory[i], tiedRounds[i])
+				}
+			}
+			if !reflect.DeepEqual(g.Votes, tt.rounds[len(tt.rounds)-1]) {
+				t.Errorf("got final ballots %v, want %v", g.Votes, tt.rounds[len(tt.rounds)-1])
+			}
+		})
+	}
+}
+
+// A tie in the last round ends the game without anyone voted out, so the spy wins
+func TestComputeResultAfterLastTie(t *testing.T) {
+	g := &models.Game{SpyID: "spy", VoteRound: MaxVoteRounds, Votes: map[string]string{"a": "b", "b": "spy", "spy": "a"}}
+	if !CloseVoteRound(g) {
+		t.Fatal("a tie in the last round did not finish the game")
+	}
+	players, scores := newTestPlayers("a", "b", "spy")
+	result := ComputeResult(g, spyfall.New(nil, nil), players, scores)
+	if !result.IsTie || result.MostVoted != "" || result.InnocentWon {
+		t.Fatalf("got tie %v, most voted %q, innocents won %v; want a tie the spy wins", result.IsTie, result.MostVoted, result.InnocentWon)
+	}
+}
+
+func TestComputeResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        modes.Mode
+		game        models.Game
+		players     []string // lobby members when the game finishes
+		mostVoted   string
+		tie         bool
+		innocentWon bool
+		winners     []string
+	}{
+		{
+			name:        "spy voted out",
+			mode:        spyfall.New(nil, nil),
+			game:        models.Game{SpyID: "spy", Votes: map[string]string{"a": "spy", "b": "spy", "spy": "a"}},
+			players:     []string{"a", "b", "spy"},
+			mostVoted:   "spy",
+			innocentWon: true,
+			winners:     []string{"a", "b"},
+		},
+		{
+			name:        "innocent voted out",
+			mode:        spyfall.New(nil, nil),
+			game:        models.Game{SpyID: "spy", Votes: map[string]string{"a": "b", "b": "a", "spy": "b"}},
+			players:     []string{"a", "b", "spy"},
+			mostVoted:   "b",
+			innocentWon: false,
+			winners:     []string{"spy"},
+		},
+		{
+			name:        "undercover voted out",
+			mode:        undercover.New(nil),
+			game:        models.Game{SpyID: "uc", MrWhiteID: "mw", Votes: map[string]string{"a": "uc", "b": "uc", "uc": "mw", "mw": "a"}},
+			players:     []string{"a", "b", "uc", "mw"},
+			mostVoted:   "uc",
+			innocentWon: true,
+			winners:     []string{"a", "b"},
+		},
+		{
+			name:        "Mr. White voted out",
+			mode:        undercover.New(nil),
+			game:        models.Game{SpyID: "uc", MrWhiteID: "mw", Votes: map[string]string{"a": "mw", "b": "mw", "uc": "mw", "mw": "a"}},
+			players:     []string{"a", "b", "uc", "mw"},
+			mostVoted:   "mw",
+			innocentWon: false,
+			winners:     []string{"uc", "mw"},
+		},
+		{
+			name:        "spy forfeited",
+			mode:        spyfall.New(nil, nil),
+			game:        models.Game{SpyID: "spy", SpyForfeited: true, Votes: map[string]string{"a": "b"}},
+			players:     []string{"a", "b", "c"},
+			innocentWon: true,
+			winners:     []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players, scores := newTestPlayers(tt.players...)
+			result := ComputeResult(&tt.game, tt.mode, players, scores)
+
+			if result.MostVoted != tt.mostVoted || result.IsTie != tt.tie || result.InnocentWon != tt.innocentWon {
+				t.Fatalf("got most voted %q, tie %v, innocents won %v; want %q, %v, %v",
+					result.MostVoted, result.IsTie, result.InnocentWon, tt.mostVoted, tt.tie, tt.innocentWon)
+			}
+			if result.SpyForfeited != tt.game.SpyForfeited {
+				t.Errorf("got spy forfeited %v, want %v", result.SpyForfeited, tt.game.SpyForfeited)
+			}
+			won := make(map[string]bool)
+			for _, id := range tt.winners {
+				won[id] = true
+			}
+			for _, id := range tt.players {
+				want := models.PlayerScore{GamesLost: 1}
+				if won[id] {
+					want = models.PlayerScore{GamesWon: 1}
+				}
+				if got := result.ScoreDeltas[id]; got != want {
+					t.Errorf("score delta of %s: got %+v, want %+v", id, got, want)
+				}
+			}
+			if len(result.ScoreDeltas) != len(tt.players) {
+				t.Errorf("got %d score deltas, want one per remaining player (%d)", len(result.ScoreDeltas), len(tt.players))
+			}
+			for voter, suspect := range tt.game.Votes {
+				if got, want := result.VotedCorrectly[voter], tt.mode.IsImpostor(&tt.game, suspect); got != want {
+					t.Errorf("voted correctly of %s: got %v, want %v", voter, got, want)
+				}
+			}
+		})
+	}
+}
+
+// The frozen result must not change when the game is touched after it finished, e.g. a player leaving
+func TestComputeResultDoesNotShareGameState(t *testing.T) {
+	g := &models.Game{SpyID: "spy", Votes: map[string]string{"a": "spy", "b": "spy", "spy": "a"}}
+	players, scores := newTestPlayers("a", "b", "spy")
+	result := ComputeResult(g, spyfall.New(nil, nil), players, scores)
+
+	delete(g.Votes, "a")
+	g.Votes["b"] = "a"
+	delete(players, "b")
+
+	if result.VoteCount["spy"] != 2 || result.MostVoted != "spy" || !result.InnocentWon {
+		t.Fatalf("result changed with the game: %+v", result)
+	}
+	if _, ok := result.ScoreDeltas["b"]; !ok {
+		t.Fatal("score delta of a player who left afterwards is gone")
+	}
+}
@@ -25,7 +25,7 @@ func GenerateRoomCode() string {
 }
 
 // GetUniqueRoomCode generates a unique room code
-func GetUniqueRoomCode(lobbyStore *store.LobbyStore) string {
+func GetUniqueRoomCode(lobbyStore store.LobbyStore) string {
 	for {
 		code := GenerateRoomCode()
 		if !lobbyStore.Exists(code) {
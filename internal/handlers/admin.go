@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/render"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+)
+
+// adminCSRF rejects cross-site form posts, which browsers would send with the cached admin credentials
+var adminCSRF = http.NewCrossOriginProtection()
+
+// adminLobbyRow is one lobby of the admin overview
+type adminLobbyRow struct {
+	Code    string
+	Mode    string
+	Phase   models.GameStatus
+	Humans  int
+	Bots    int
+	Clients int
+	Age     string
+	Idle    string
+
+	created time.Time
+}
+
+// adminPlayerRow is one player of the admin lobby view
+type adminPlayerRow struct {
+	ID       string
+	Name     string
+	Host     bool
+	Bot      bool
+	LastSeen string // empty without a live connection to this instance
+	Status   string // ready or voted in the current phase
+	Record   string
+	Role     string // only filled when roles are shown
+	Impostor bool
+}
+
+// adminConnectionRow is one live connection of the admin lobby view
+type adminConnectionRow struct {
+	Player    string
+	Transport string
+	Connected string
+	LastSeen  string
+}
+
+// HandleAdmin serves the operator dashboard under /admin
+// It is disabled unless ADMIN_TOKEN is set; the token is the password of HTTP basic auth (any user name)
+// or a bearer token.
+func (ctx *Context) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	if ctx.AdminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !ctx.adminAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := adminCSRF.Check(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin"), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "":
+		if adminMethod(w, r, http.MethodGet) {
+			ctx.adminOverview(w)
+		}
+	case path == "notice":
+		if adminMethod(w, r, http.MethodPost) {
+			ctx.adminNotice(w, r)
+		}
+	case len(parts) == 2 && parts[0] == "lobbies":
+		if adminMethod(w, r, http.MethodGet) {
+			ctx.adminLobby(w, r, strings.ToUpper(parts[1]))
+		}
+	case len(parts) == 3 && parts[0] == "lobbies" && parts[2] == "close":
+		if adminMethod(w, r, http.MethodPost) {
+			ctx.adminCloseLobby(w, r, strings.ToUpper(parts[1]))
+		}
+	case len(parts) == 3 && parts[0] == "lobbies" && parts[2] == "kick":
+		if adminMethod(w, r, http.MethodPost) {
+			ctx.adminKick(w, r, strings.ToUpper(parts[1]))
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// adminAuthorized checks the admin token of a request in constant time
+func (ctx *Context) adminAuthorized(r *http.Request) bool {
+	var secret string
+	if _, password, ok := r.BasicAuth(); ok {
+		secret = password
+	} else if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		secret = strings.TrimSpace(token)
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(ctx.AdminToken)) == 1
+}
+
+// adminMethod rejects requests with another method than the route's
+func adminMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+// adminOverview lists every lobby with its phase, players and live connections
+func (ctx *Context) adminOverview(w http.ResponseWriter) {
+	now := time.Now()
+	var rows []adminLobbyRow
+	for code, lobby := range ctx.LobbyStore.All() {
+		lobby.RLock()
+		row := adminLobbyRow{
+			Code:    code,
+			Mode:    ctx.Modes.Get(lobby.Mode).Name(),
+			Phase:   models.StatusWaiting,
+			Humans:  lobby.HumanCount(),
+			Clients: sse.ClientCount(code),
+			Idle:    formatAge(now.Sub(lobby.LastActivity())),
+			created: lobby.CreatedAt,
+		}
+		row.Bots = len(lobby.Players) - row.Humans
+		if g := lobby.CurrentGame; g != nil {
+			row.Phase = g.Status
+		}
+		if !lobby.CreatedAt.IsZero() {
+			row.Age = formatAge(now.Sub(lobby.CreatedAt))
+		}
+		lobby.RUnlock()
+		rows = append(rows, row)
+	}
+	// Newest first
+	slices.SortFunc(rows, func(a, b adminLobbyRow) int {
+		if c := b.created.Compare(a.created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	data := struct {
+		Lobbies []adminLobbyRow
+		Players int
+		Clients int
+		Notice  string
+	}{
+		Lobbies: rows,
+		Notice:  ctx.Notice(),
+	}
+	for _, row := range rows {
+		data.Players += row.Humans
+		data.Clients += row.Clients
+	}
+	ctx.Templates.ExecuteTemplate(w, "admin.html", data)
+}
+
+// adminLobby shows the state of one lobby; roles and secrets stay hidden unless ?roles=1 is set
+// API tokens are never shown
+func (ctx *Context) adminLobby(w http.ResponseWriter, r *http.Request, roomCode string) {
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	showRoles := r.URL.Query().Get("roles") == "1"
+	now := time.Now()
+
+	lobby.RLock()
+	defer lobby.RUnlock()
+
+	g := lobby.CurrentGame
+	mode := ctx.Modes.Get(lobby.Mode)
+	if g != nil {
+		mode = ctx.Modes.Get(g.Mode)
+	}
+
+	var players []adminPlayerRow
+	names := make(map[string]string, len(lobby.Players))
+	for _, p := range render.GetPlayerList(lobby.Players) {
+		names[p.ID] = p.Name
+		row := adminPlayerRow{ID: p.ID, Name: p.Name, Host: p.ID == lobby.Host, Bot: p.IsBot}
+		if seen, ok := lobby.PlayerLastSeen(p.ID); ok {
+			row.LastSeen = formatAge(now.Sub(seen)) + " ago"
+		}
+		if s, ok := lobby.Scores[p.ID]; ok {
+			row.Record = fmt.Sprintf("%d–%d", s.GamesWon, s.GamesLost)
+		}
+		if g != nil {
+			if g.Status == models.StatusVoting {
+				if g.Votes[p.ID] != "" {
+					row.Status = "voted"
+				}
+			} else if game.GetReadyStateMap(g)[p.ID] {
+				row.Status = "ready"
+			}
+			if showRoles && g.Status != models.StatusReadyCheck {
+				row.Role = mode.RoleName(g, p.ID)
+				row.Impostor = mode.IsImpostor(g, p.ID)
+			}
+		}
+		players = append(players, row)
+	}
+
+	var connections []adminConnectionRow
+	clients := lobby.SSEClients()
+	slices.SortFunc(clients, func(a, b models.SSEClient) int { return a.ConnectedAt.Compare(b.ConnectedAt) })
+	for _, c := range clients {
+		name, ok := names[c.PlayerID]
+		if !ok {
+			name = "(not in lobby)"
+		}
+		connections = append(connections, adminConnectionRow{
+			Player:    name,
+			Transport: c.Transport,
+			Connected: formatAge(now.Sub(c.ConnectedAt)) + " ago",
+			LastSeen:  formatAge(now.Sub(c.LastSeen)) + " ago",
+		})
+	}
+
+	var options []string
+	for _, opt := range mode.Options() {
+		if lobby.Options[opt.Key] {
+			options = append(options, opt.Label)
+		}
+	}
+
+	data := struct {
+		Code        string
+		Mode        string
+		Options     []string
+		Phase       models.GameStatus
+		VoteRound   int
+		Created     string
+		Idle        string
+		Games       int
+		Players     []adminPlayerRow
+		Connections []adminConnectionRow
+		ShowRoles   bool
+		Secrets     []models.Secret
+	}{
+		Code:        roomCode,
+		Mode:        mode.Name(),
+		Options:     options,
+		Phase:       models.StatusWaiting,
+		Idle:        formatAge(now.Sub(lobby.LastActivity())),
+		Games:       len(lobby.History),
+		Players:     players,
+		Connections: connections,
+		ShowRoles:   showRoles,
+	}
+	if !lobby.CreatedAt.IsZero() {
+		data.Created = formatAge(now.Sub(lobby.CreatedAt)) + " ago"
+	}
+	if g != nil {
+		data.Phase = g.Status
+		data.VoteRound = g.VoteRound
+		if showRoles {
+			data.Secrets = mode.Secrets(g)
+		}
+	}
+	ctx.Templates.ExecuteTemplate(w, "admin_lobby.html", data)
+}
+
+// adminNotice sets the maintenance message shown to every connected client (an empty message clears it)
+func (ctx *Context) adminNotice(w http.ResponseWriter, r *http.Request) {
+	message := strings.TrimSpace(r.FormValue("message"))
+	ctx.notice.Store(message)
+	logging.From(r).Info("Admin set server notice", "message", message)
+
+	for _, lobby := range ctx.LobbyStore.All() {
+		sse.Publish(lobby, events.ServerNotice{Message: message})
+	}
+
+	w.Header().Set("HX-Redirect", "/admin")
+	w.WriteHeader(http.StatusOK)
+}
+
+// adminCloseLobby sends every client of a lobby home and deletes it
+func (ctx *Context) adminCloseLobby(w http.ResponseWriter, r *http.Request, roomCode string) {
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+	logging.From(r).Info("Admin closed lobby", "lobby", roomCode)
+	ctx.closeLobby(roomCode, lobby, "admin")
+
+	w.Header().Set("HX-Redirect", "/admin")
+	w.WriteHeader(http.StatusOK)
+}
+
+// adminKick removes the player in the player_id form field from a lobby
+func (ctx *Context) adminKick(w http.ResponseWriter, r *http.Request, roomCode string) {
+	playerID := r.FormValue("player_id")
+	if err := ctx.kickPlayer(playerLogger(r, roomCode, playerID), roomCode, playerID); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	// Kicking the last human deletes the lobby
+	to := "/admin/lobbies/" + roomCode
+	if !ctx.LobbyStore.Exists(roomCode) {
+		to = "/admin"
+	}
+	w.Header().Set("HX-Redirect", to)
+	w.WriteHeader(http.StatusOK)
+}
+
+// formatAge renders a duration coarsely, e.g. "45s", "12m" or "3h 05m"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/export"
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+	"github.com/aaronzipp/you-are-officially-sus/internal/render"
+	"github.com/google/uuid"
+)
+
+// apiMaxBodySize limits the size of an API request body
+const apiMaxBodySize = 4096
+
+// HandleAPI serves the versioned JSON API under /api/v1/
+// Routes: POST /lobbies, GET /lobbies/{code}, GET /lobbies/{code}/results,
+// POST /lobbies/{code}/{join|leave|start|restart|ready|vote} and GET /openapi.yaml
+func (ctx *Context) HandleAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, api.Version), "/")
+	parts := strings.Split(path, "/")
+	logging.From(r).Debug("HandleAPI", "method", r.Method, "path", r.URL.Path)
+
+	switch {
+	case path == "openapi.yaml":
+		if !apiMethod(w, r, http.MethodGet) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(api.OpenAPI)
+	case path == "lobbies":
+		if !apiMethod(w, r, http.MethodPost) {
+			return
+		}
+		ctx.apiCreateLobby(w, r)
+	case len(parts) == 2 && parts[0] == "lobbies":
+		if !apiMethod(w, r, http.MethodGet) {
+			return
+		}
+		ctx.apiLobbyAction(w, r, parts[1], "")
+	case len(parts) == 3 && parts[0] == "lobbies":
+		action := parts[2]
+		method := http.MethodPost
+		if action == "results" {
+			method = http.MethodGet
+		}
+		if !apiMethod(w, r, method) {
+			return
+		}
+		ctx.apiLobbyAction(w, r, parts[1], action)
+	default:
+		writeAPIError(w, http.StatusNotFound, api.CodeNotFound, "Unknown API route")
+	}
+}
+
+// apiCreateLobby creates a lobby hosted by a new player and returns their session
+func (ctx *Context) apiCreateLobby(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateLobbyRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	playerID := uuid.New().String()
+	lobby, err := ctx.createLobby(logging.From(r).With("player", playerID), playerID, strings.TrimSpace(req.Name))
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	var token string
+	err = ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		token = issueToken(lobby, playerID)
+		return nil
+	})
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusCreated, api.Session{Token: token, PlayerID: playerID, Lobby: ctx.apiLobbyView(lobby, playerID)})
+}
+
+// apiLobbyAction runs an action on a lobby ("" reads its state)
+func (ctx *Context) apiLobbyAction(w http.ResponseWriter, r *http.Request, roomCode, action string) {
+	lobby, exists := ctx.LobbyStore.Get(strings.ToUpper(roomCode))
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, api.CodeNotFound, "Lobby not found")
+		return
+	}
+	roomCode = lobby.Code
+
+	// Joining is the only action for players without a session
+	if action == "join" {
+		ctx.apiJoinLobby(w, r, lobby)
+		return
+	}
+
+	playerID, ok := apiPlayer(r, lobby)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		writeAPIError(w, http.StatusUnauthorized, api.CodeUnauthorized, "Send the token of a player in this lobby as a bearer token")
+		return
+	}
+	logger := playerLogger(r, roomCode, playerID)
+
+	switch action {
+	case "":
+		writeAPIJSON(w, http.StatusOK, ctx.apiLobbyView(lobby, playerID))
+	case "leave":
+		var req api.LeaveLobbyRequest
+		if !decodeAPIRequest(w, r, &req) {
+			return
+		}
+		if err := ctx.leaveLobby(logger, roomCode, playerID, req.NewHostID); err != nil {
+			writeActionError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "start":
+		if err := ctx.startGame(logger, lobby, playerID); err != nil {
+			writeActionError(w, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, ctx.apiLobbyView(lobby, playerID))
+	case "restart":
+		if err := ctx.restartGame(logger, lobby, playerID); err != nil {
+			writeActionError(w, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, ctx.apiLobbyView(lobby, playerID))
+	case "ready":
+		update, err := ctx.applyReady(logger, lobby, roomCode, playerID, true)
+		if err != nil {
+			writeActionError(w, err)
+			return
+		}
+		ctx.broadcastReady(lobby, roomCode, playerID, update)
+
+		resp := api.ReadyResponse{Phase: string(update.status), Ready: update.isReady}
+		for _, ev := range update.evs {
+			if pc, ok := ev.(events.PhaseChanged); ok {
+				resp.NextPhase = string(pc.Phase)
+			}
+		}
+		writeAPIJSON(w, http.StatusOK, resp)
+	case "vote":
+		var req api.VoteRequest
+		if !decodeAPIRequest(w, r, &req) {
+			return
+		}
+		if req.Suspect == "" {
+			writeAPIError(w, http.StatusBadRequest, api.CodeBadRequest, "Missing suspect")
+			return
+		}
+		update, err := ctx.applyVote(lobby, roomCode, playerID, req.Suspect)
+		if err != nil {
+			writeActionError(w, err)
+			return
+		}
+		ctx.broadcastVote(lobby, roomCode, update)
+
+		resp := api.VoteResponse{Revote: update.revote}
+		for _, ev := range update.evs {
+			switch e := ev.(type) {
+			case events.VoteCast:
+				resp.Round = e.Round
+			case events.GameFinished:
+				resp.Finished = true
+			}
+		}
+		writeAPIJSON(w, http.StatusOK, resp)
+	case "results":
+		ctx.apiResults(w, lobby)
+	default:
+		writeAPIError(w, http.StatusNotFound, api.CodeNotFound, "Unknown API route")
+	}
+}
+
+// apiJoinLobby adds a new player to a lobby and returns their session
+func (ctx *Context) apiJoinLobby(w http.ResponseWriter, r *http.Request, lobby *models.Lobby) {
+	var req api.JoinLobbyRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	playerID := uuid.New().String()
+	if err := ctx.joinLobby(playerLogger(r, lobby.Code, playerID), lobby, playerID, strings.TrimSpace(req.Name), false); err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	var token string
+	err := ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		token = issueToken(lobby, playerID)
+		return nil
+	})
+	if err != nil {
+		writeActionError(w, err)
+		return
+	}
+
+	writeAPIJSON(w, http.StatusCreated, api.Session{Token: token, PlayerID: playerID, Lobby: ctx.apiLobbyView(lobby, playerID)})
+}
+
+// apiResults returns the outcome of the finished game and the lobby scores
+func (ctx *Context) apiResults(w http.ResponseWriter, lobby *models.Lobby) {
+	lobby.RLock()
+	defer lobby.RUnlock()
+
+	g := lobby.CurrentGame
+	if g == nil || g.Status != models.StatusFinished || len(lobby.History) == 0 {
+		writeAPIError(w, http.StatusConflict, api.CodeWrongPhase, "The game is not finished")
+		return
+	}
+	// Finished games are archived when they end, so the current one is the last record
+	writeAPIJSON(w, http.StatusOK, api.Results{Game: export.FromRecord(lobby.History[len(lobby.History)-1]), Scores: export.Scores(lobby)})
+}
+
+// apiLobbyView builds the state of a lobby as seen by one player
+func (ctx *Context) apiLobbyView(lobby *models.Lobby, playerID string) api.Lobby {
+	lobby.RLock()
+	defer lobby.RUnlock()
+
+	view := api.Lobby{
+		Code:    lobby.Code,
+		Phase:   string(models.StatusWaiting),
+		HostID:  lobby.Host,
+		Mode:    string(lobby.Mode),
+		Options: maps.Clone(lobby.Options),
+		Players: []api.Player{},
+		Scores:  export.Scores(lobby),
+	}
+	for _, p := range render.GetPlayerList(lobby.Players) {
+		view.Players = append(view.Players, api.Player{ID: p.ID, Name: p.Name, Bot: p.IsBot})
+	}
+
+	g := lobby.CurrentGame
+	if g == nil {
+		return view
+	}
+	view.Phase = string(g.Status)
+	view.Mode = string(g.Mode)
+	gv := &api.Game{
+		Phase:     string(g.Status),
+		VoteRound: g.VoteRound,
+		Votes:     len(g.Votes),
+		Total:     len(lobby.Players),
+		You:       api.You{Voted: g.Votes[playerID] != ""},
+	}
+	if ready := game.GetReadyStateMap(g); ready != nil {
+		gv.Ready = game.CountReadyPlayers(ready, lobby.Players)
+		gv.You.Ready = ready[playerID]
+	}
+	if g.Status != models.StatusReadyCheck {
+		if p, ok := lobby.Players[g.FirstQuestioner]; ok {
+			gv.FirstQuestioner = p.Name
+		}
+		gv.You.Role = apiRole(ctx.Modes.Get(g.Mode).SecretView(g, playerID))
+	}
+	view.Game = gv
+	return view
+}
+
+// apiRole converts the role card of a player
+func apiRole(secret modes.SecretView) *api.Role {
+	role := &api.Role{Title: secret.Title, Spy: secret.Spy, Hint: secret.Hint, Details: []api.Detail{}}
+	for _, d := range secret.Details {
+		role.Details = append(role.Details, api.Detail{Label: d.Label, Value: d.Value})
+	}
+	if secret.Reminder.Label != "" {
+		role.Reminder = &api.Detail{Label: secret.Reminder.Label, Value: secret.Reminder.Value}
+	}
+	return role
+}
+
+// apiPlayer authenticates an API request: a bearer token of the lobby, or else the player_id cookie of a member
+func apiPlayer(r *http.Request, lobby *models.Lobby) (string, bool) {
+	lobby.RLock()
+	defer lobby.RUnlock()
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return "", false
+		}
+		playerID, ok := lobby.Tokens[hashToken(strings.TrimSpace(token))]
+		if !ok {
+			return "", false
+		}
+		_, member := lobby.Players[playerID]
+		return playerID, member
+	}
+
+	cookie, err := r.Cookie("player_id")
+	if err != nil {
+		return "", false
+	}
+	_, member := lobby.Players[cookie.Value]
+	return cookie.Value, member
+}
+
+// issueToken creates a bearer token for a player; only its hash is kept in the lobby
+// Caller must hold lobby lock
+func issueToken(lobby *models.Lobby, playerID string) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if lobby.Tokens == nil {
+		lobby.Tokens = make(map[string]string)
+	}
+	lobby.Tokens[hashToken(token)] = playerID
+	return token
+}
+
+// revokeTokens forgets the tokens of a player who left (caller must hold lobby lock)
+func revokeTokens(lobby *models.Lobby, playerID string) {
+	for hash, id := range lobby.Tokens {
+		if id == playerID {
+			delete(lobby.Tokens, hash)
+		}
+	}
+}
+
+// hashToken returns the key of a token in Lobby.Tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiMethod rejects requests with another method than want
+func apiMethod(w http.ResponseWriter, r *http.Request, want string) bool {
+	if r.Method == want {
+		return true
+	}
+	w.Header().Set("Allow", want)
+	writeAPIError(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed, "Method not allowed")
+	return false
+}
+
+// decodeAPIRequest reads a JSON request body into v; an empty body leaves v unchanged
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, api.CodeBadRequest, "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeAPIJSON writes a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("HandleAPI: writing response failed", "err", err)
+	}
+}
+
+// writeAPIError writes an api.Error
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIJSON(w, status, api.Error{Error: api.ErrorBody{Code: code, Message: message}})
+}
+
+// writeActionError writes the api.Error of a rejected lobby action
+func writeActionError(w http.ResponseWriter, err error) {
+	writeAPIError(w, errorStatus(err), errorCode(err), err.Error())
+}
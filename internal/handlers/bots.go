@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+	"github.com/google/uuid"
+)
+
+// botNames are handed out to bots in order, skipping names already taken
+var botNames = []string{"Robo Rita", "Beep Boop", "Sir Clanks", "Unit 42", "Sprocket", "Gizmo", "Byte", "Chip"}
+
+// HandleAddBot adds a bot player to the lobby (host only, between games)
+func (ctx *Context) HandleAddBot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomCode := strings.TrimPrefix(r.URL.Path, "/add-bot/")
+
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+
+	// Get player ID from cookie
+	cookie, err := r.Cookie("player_id")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	playerID := cookie.Value
+
+	botID := "bot-" + uuid.New().String()
+	var botName string
+	err = ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		if lobby.Host != playerID {
+			return reject(http.StatusForbidden, api.CodeForbidden, "Only host can add bots")
+		}
+		if lobby.CurrentGame != nil {
+			return reject(http.StatusBadRequest, api.CodeGameInProgress, "Game in progress")
+		}
+		if len(lobby.Players)-lobby.HumanCount() >= game.MaxBots {
+			return reject(http.StatusBadRequest, api.CodeBadRequest, "Too many bots")
+		}
+
+		botName = newBotName(lobby.Players)
+		lobby.Players[botID] = &models.Player{ID: botID, Name: botName, IsBot: true}
+		lobby.Scores[botID] = &models.PlayerScore{}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	playerLogger(r, roomCode, playerID).Info("Bot added", "bot", botID, "name", botName)
+
+	sse.Publish(lobby, events.PlayerJoined{PlayerID: botID, Name: botName, Bot: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newBotName picks the first free name from botNames, or "Bot N" once players have taken them all
+func newBotName(players map[string]*models.Player) string {
+	for _, name := range botNames {
+		if !isNameTaken(players, name, "") {
+			return name
+		}
+	}
+	for n := 1; ; n++ {
+		if name := fmt.Sprintf("Bot %d", n); !isNameTaken(players, name, "") {
+			return name
+		}
+	}
+}
+
+// HandleRemoveBot removes a bot player from the lobby (host only, between games)
+func (ctx *Context) HandleRemoveBot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomCode := strings.TrimPrefix(r.URL.Path, "/remove-bot/")
+
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+
+	// Get player ID from cookie
+	cookie, err := r.Cookie("player_id")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	playerID := cookie.Value
+
+	r.ParseForm()
+	botID := r.FormValue("bot_id")
+
+	var bot *models.Player
+	err = ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		if lobby.Host != playerID {
+			return reject(http.StatusForbidden, api.CodeForbidden, "Only host can remove bots")
+		}
+		if lobby.CurrentGame != nil {
+			return reject(http.StatusBadRequest, api.CodeGameInProgress, "Game in progress")
+		}
+		var exists bool
+		bot, exists = lobby.Players[botID]
+		if !exists || !bot.IsBot {
+			return reject(http.StatusBadRequest, api.CodeBadRequest, "Bot not found")
+		}
+		delete(lobby.Players, botID)
+		delete(lobby.Scores, botID)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	playerLogger(r, roomCode, playerID).Info("Bot removed", "bot", botID, "name", bot.Name)
+
+	sse.Publish(lobby, events.PlayerLeft{PlayerID: botID, Name: bot.Name, Bot: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isBot reports whether a player is a bot
+func (ctx *Context) isBot(lobby *models.Lobby, playerID string) bool {
+	lobby.RLock()
+	defer lobby.RUnlock()
+	p, ok := lobby.Players[playerID]
+	return ok && p.IsBot
+}
+
+// scheduleBots lets every bot act on the current phase after a short random delay
+// Bots never hold SSE connections; they re-check the game state when their timer fires
+func (ctx *Context) scheduleBots(lobby *models.Lobby, roomCode string) {
+	lobby.RLock()
+	g := lobby.CurrentGame
+	if g == nil {
+		lobby.RUnlock()
+		return
+	}
+	status, round := g.Status, g.VoteRound
+	var botIDs []string
+	for id, p := range lobby.Players {
+		if p.IsBot {
+			botIDs = append(botIDs, id)
+		}
+	}
+	lobby.RUnlock()
+
+	for _, botID := range botIDs {
+		delay := time.Duration(game.BotMinDelayMillis+rand.Intn(game.BotMaxDelayMillis-game.BotMinDelayMillis)) * time.Millisecond
+		time.AfterFunc(delay, func() {
+			ctx.botAct(lobby, roomCode, botID, status, round)
+		})
+	}
+}
+
+// ResumeBots schedules bots for every game in progress, e.g. after lobbies were restored from disk
+func (ctx *Context) ResumeBots() {
+	for code, lobby := range ctx.LobbyStore.All() {
+		ctx.scheduleBots(lobby, code)
+	}
+}
+
+// botAct performs a bot's action for the phase it was scheduled in, if still relevant
+func (ctx *Context) botAct(lobby *models.Lobby, roomCode, botID string, status models.GameStatus, round int) {
+	// Lobby may have been closed or replaced in the meantime
+	if current, exists := ctx.LobbyStore.Get(roomCode); !exists || current != lobby {
+		return
+	}
+
+	lobby.RLock()
+	g := lobby.CurrentGame
+	_, member := lobby.Players[botID]
+	stale := g == nil || !member || g.Status != status || g.VoteRound != round
+	alreadyDone := false
+	humanReady := false
+	suspectID := ""
+	if !stale {
+		switch status {
+		case models.StatusVoting:
+			alreadyDone = g.Votes[botID] != ""
+			suspectID = chooseBotVote(g, lobby.Players, botID)
+		default:
+			readyMap := game.GetReadyStateMap(g)
+			alreadyDone = readyMap[botID]
+			for id, p := range lobby.Players {
+				if !p.IsBot && readyMap[id] {
+					humanReady = true
+				}
+			}
+		}
+	}
+	lobby.RUnlock()
+
+	if stale || alreadyDone {
+		return
+	}
+
+	logger := slog.With("lobby", roomCode, "player", botID)
+	switch status {
+	case models.StatusVoting:
+		if suspectID == "" {
+			return
+		}
+		update, err := ctx.applyVote(lobby, roomCode, botID, suspectID)
+		if err != nil {
+			return
+		}
+		logger.Debug("Bot voted", "suspect", suspectID)
+		ctx.broadcastVote(lobby, roomCode, update)
+	case models.StatusPlaying:
+		// Bots only get ready to vote once a human wants to vote, so the discussion isn't cut short
+		if !humanReady {
+			return
+		}
+		fallthrough
+	default:
+		update, err := ctx.applyReady(logger, lobby, roomCode, botID, false)
+		if err != nil {
+			return
+		}
+		ctx.broadcastReady(lobby, roomCode, botID, update)
+	}
+}
+
+// chooseBotVote picks a plausible suspect: often the current front-runner, otherwise a random other player
+// Caller must hold lobby lock
+func chooseBotVote(g *models.Game, players map[string]*models.Player, botID string) string {
+	candidates := make([]string, 0, len(players))
+	for id := range players {
+		if id != botID {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	voteCount := make(map[string]int)
+	leader, leaderVotes := "", 0
+	for _, suspectID := range g.Votes {
+		voteCount[suspectID]++
+		if suspectID != botID && voteCount[suspectID] > leaderVotes {
+			leader, leaderVotes = suspectID, voteCount[suspectID]
+		}
+	}
+	if leader != "" && rand.Intn(2) == 0 {
+		return leader
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+)
+
+// RenderHTML turns a lobby event into the HTMX partials the browser pages listen for
+// Registered as the sse.FormatHTML renderer; reads the current lobby state, so call it without the lock
+func (ctx *Context) RenderHTML(lobby *models.Lobby, ev events.Event) []sse.Message {
+	code := lobby.Code
+	switch e := ev.(type) {
+	case events.PlayerJoined:
+		return ctx.lobbyUpdateMessages(lobby)
+	case events.PlayerLeft:
+		msgs := ctx.lobbyUpdateMessages(lobby)
+		if e.Kicked {
+			// The removed player's pages go home
+			msgs = append(msgs, sse.Message{PlayerID: e.PlayerID, Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(code, "/")})
+		}
+		return msgs
+	case events.SettingsChanged:
+		return ctx.hostControlsMessages(lobby)
+	case events.HostChanged:
+		// The new host is told when the server picked them; a chosen successor was asked in person
+		if !e.Automatic {
+			return nil
+		}
+		return []sse.Message{{PlayerID: e.HostID, Event: sse.EventHostChanged, Data: ctx.HostNotification()}}
+	case events.PhaseChanged:
+		return []sse.Message{{Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(code, game.PhasePathFor(code, e.Phase))}}
+	case events.ReadyChanged:
+		event, label := readyCountEvent(e.Phase)
+		if event == "" {
+			return nil
+		}
+		return []sse.Message{{Event: event, Data: ctx.ReadyCount(e.Count, e.Total, label)}}
+	case events.VoteCast:
+		return []sse.Message{{Event: sse.EventVoteCount, Data: ctx.VoteCount(e.Count, e.Total)}}
+	case events.GameFinished:
+		return []sse.Message{{Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(code, game.PhasePathFor(code, models.StatusFinished))}}
+	case events.GameAborted:
+		return []sse.Message{{Event: sse.EventErrorMessage, Data: ctx.GameAbortedMessage(e.Reason)}}
+	case events.LobbyClosed:
+		return []sse.Message{{Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(code, "/")}}
+	case events.ServerNotice:
+		return []sse.Message{{Event: sse.EventServerNotice, Data: ctx.ServerNotice(e.Message)}}
+	case events.Snapshot:
+		return ctx.snapshotMessages(lobby, e)
+	}
+	return nil
+}
+
+// readyCountEvent returns the SSE event and count label of a phase's ready count
+func readyCountEvent(phase models.GameStatus) (event, label string) {
+	switch phase {
+	case models.StatusReadyCheck:
+		return "ready-count-check", "players ready"
+	case models.StatusRoleReveal:
+		return "ready-count-reveal", "players ready"
+	case models.StatusPlaying:
+		return "ready-count-playing", "players ready to vote"
+	}
+	return "", ""
+}
+
+// lobbyUpdateMessages refreshes the player list, scores and host controls
+func (ctx *Context) lobbyUpdateMessages(lobby *models.Lobby) []sse.Message {
+	lobby.RLock()
+	msgs := []sse.Message{
+		{Event: sse.EventPlayerUpdate, Data: ctx.PlayerList(lobby.Players)},
+		{Event: sse.EventScoreUpdate, Data: ctx.ScoreTable(lobby)},
+	}
+	lobby.RUnlock()
+	return append(msgs, ctx.hostControlsMessages(lobby)...)
+}
+
+// hostControlsMessages renders the host controls for every human player (they differ for the host)
+func (ctx *Context) hostControlsMessages(lobby *models.Lobby) []sse.Message {
+	lobby.RLock()
+	defer lobby.RUnlock()
+	msgs := make([]sse.Message, 0, len(lobby.Players))
+	for id, p := range lobby.Players {
+		if !p.IsBot {
+			msgs = append(msgs, sse.Message{PlayerID: id, Event: sse.EventControlsUpdate, Data: ctx.HostControls(lobby, id)})
+		}
+	}
+	return msgs
+}
+
+// snapshotMessages renders the state a page starts with: the lobby lists, or the count of the current phase
+func (ctx *Context) snapshotMessages(lobby *models.Lobby, s events.Snapshot) []sse.Message {
+	total := len(s.Players)
+	switch s.Phase {
+	case models.StatusWaiting:
+		msgs := ctx.lobbyUpdateMessages(lobby)
+		if msgs[1].Data == "" {
+			// No scores yet
+			msgs = append(msgs[:1], msgs[2:]...)
+		}
+		return msgs
+	case models.StatusVoting:
+		return []sse.Message{{Event: sse.EventVoteCount, Data: ctx.VoteCount(s.Votes, total)}}
+	}
+	event, label := readyCountEvent(s.Phase)
+	if event == "" {
+		return nil
+	}
+	return []sse.Message{{Event: event, Data: ctx.ReadyCount(s.Ready, total, label)}}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/export"
+)
+
+// HandleExport lets the host download the lobby scores and finished games
+// /export/{code}/lobby.json, /export/{code}/scores.csv or /export/{code}/games.csv
+func (ctx *Context) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/export/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	roomCode, file := parts[0], parts[1]
+
+	lobby, playerID, err := ctx.getLobbyAndPlayer(r, roomCode)
+	if err != nil {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+
+	lobby.RLock()
+	if lobby.Host != playerID {
+		lobby.RUnlock()
+		http.Error(w, "Only host can export", http.StatusForbidden)
+		return
+	}
+	data := export.FromLobby(lobby, time.Now())
+	lobby.RUnlock()
+
+	filename := fmt.Sprintf("sus-%s-%s", roomCode, file)
+	switch file {
+	case "lobby.json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(data)
+	case "scores.csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		err = export.WriteScoresCSV(w, data)
+	case "games.csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		err = export.WriteGamesCSV(w, data)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		playerLogger(r, roomCode, playerID).Warn("Export failed", "file", file, "err", err)
+		return
+	}
+	playerLogger(r, roomCode, playerID).Info("Lobby exported", "file", file)
+}
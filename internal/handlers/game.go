@@ -1,25 +1,24 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"math/rand"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
 	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/metrics"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
 	"github.com/aaronzipp/you-are-officially-sus/internal/render"
 	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
 )
 
-var debug bool
-
-func init() {
-	debug = os.Getenv("DEBUG") != ""
-}
-
 // HandleGameMux routes game subpaths by phase and actions
 func (ctx *Context) HandleGameMux(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/game/")
@@ -98,7 +97,7 @@ func (ctx *Context) HandleGameMux(w http.ResponseWriter, r *http.Request) {
 	// Build page using per-phase template
 	lobby.RLock()
 	g = lobby.CurrentGame
-	playerInfo := g.PlayerInfo[playerID]
+	mode := ctx.Modes.Get(g.Mode)
 
 	isReady := false
 	switch g.Status {
@@ -116,9 +115,8 @@ func (ctx *Context) HandleGameMux(w http.ResponseWriter, r *http.Request) {
 		Status          models.GameStatus
 		Players         []*models.Player
 		TotalPlayers    int
-		Location        *models.Location
-		Challenge       string
-		IsSpy           bool
+		Secret          modes.SecretView
+		Text            modes.Text
 		IsReady         bool
 		HasVoted        bool
 		VoteRound       int
@@ -131,9 +129,8 @@ func (ctx *Context) HandleGameMux(w http.ResponseWriter, r *http.Request) {
 		Status:          g.Status,
 		Players:         render.GetPlayerList(lobby.Players),
 		TotalPlayers:    len(lobby.Players),
-		Location:        g.Location,
-		Challenge:       playerInfo.Challenge,
-		IsSpy:           playerInfo.IsSpy,
+		Secret:          mode.SecretView(g, playerID),
+		Text:            mode.Text(),
 		IsReady:         isReady,
 		HasVoted:        g.Votes[playerID] != "",
 		VoteRound:       g.VoteRound,
@@ -178,95 +175,145 @@ func (ctx *Context) gameHandleReadyCookie(w http.ResponseWriter, r *http.Request
 	}
 	playerID := cookie.Value
 
-	// Values derived from server state only
-	var readyCountMsg string
-	var buttonHTML string
-	var shouldBroadcastPhase bool
-	var readyCountEventName string
+	update, err := ctx.applyReady(playerLogger(r, roomCode, playerID), lobby, roomCode, playerID, true)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	ctx.broadcastReady(lobby, roomCode, playerID, update)
+
+	// If phase advanced, also ensure the initiating client navigates via HX-Redirect
+	if update.nextPath != "" {
+		w.Header().Set("HX-Redirect", update.nextPath)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(readyButtonHTML(update.status, update.isReady)))
+}
 
+// readyUpdate is the server-derived outcome of a readiness change
+type readyUpdate struct {
+	status   models.GameStatus // phase the change applied to
+	isReady  bool              // player's readiness after the change
+	nextPath string            // set when the phase advanced
+	evs      []events.Event    // published once the lobby lock is released
+}
+
+// applyReady updates a player's readiness for the current phase and advances the phase when enough players are ready
+// With toggle=false an already-ready player stays ready (used by bots)
+func (ctx *Context) applyReady(logger *slog.Logger, lobby *models.Lobby, roomCode, playerID string, toggle bool) (*readyUpdate, error) {
+	var update *readyUpdate
+	err := ctx.updateLobby(lobby, func() (err error) {
+		update, err = ctx.readyChange(logger, lobby, roomCode, playerID, toggle)
+		return err
+	})
+	return update, err
+}
+
+// readyChange is the change saved by applyReady
+func (ctx *Context) readyChange(logger *slog.Logger, lobby *models.Lobby, roomCode, playerID string, toggle bool) (*readyUpdate, error) {
 	lobby.Lock()
+	defer lobby.Unlock()
+
 	g := lobby.CurrentGame
 	if g == nil {
-		lobby.Unlock()
-		http.Error(w, "No game in progress", http.StatusBadRequest)
-		return
+		return nil, reject(http.StatusConflict, api.CodeWrongPhase, "No game in progress")
 	}
 
 	statusBefore := g.Status
 
 	// Update readiness per phase rules (toggle in all phases to surface issues)
-	var isReady bool
-	var prev bool
-	var readyStateMap map[string]bool
-	switch statusBefore {
-	case models.StatusReadyCheck:
-		readyStateMap = g.ReadyToReveal
-		prev = g.ReadyToReveal[playerID]
-		g.ReadyToReveal[playerID] = !g.ReadyToReveal[playerID]
-		isReady = g.ReadyToReveal[playerID]
-	case models.StatusRoleReveal:
-		readyStateMap = g.ReadyAfterReveal
-		prev = g.ReadyAfterReveal[playerID]
-		g.ReadyAfterReveal[playerID] = !g.ReadyAfterReveal[playerID]
-		isReady = g.ReadyAfterReveal[playerID]
-	case models.StatusPlaying:
-		readyStateMap = g.ReadyToVote
-		prev = g.ReadyToVote[playerID]
-		g.ReadyToVote[playerID] = !g.ReadyToVote[playerID]
-		isReady = g.ReadyToVote[playerID]
-	default:
-		lobby.Unlock()
-		http.Error(w, "Invalid game phase", http.StatusBadRequest)
-		return
+	readyStateMap := game.GetReadyStateMap(g)
+	if readyStateMap == nil {
+		return nil, reject(http.StatusConflict, api.CodeWrongPhase, "Invalid game phase")
 	}
+	prev := readyStateMap[playerID]
+	if toggle {
+		readyStateMap[playerID] = !prev
+	} else {
+		readyStateMap[playerID] = true
+	}
+	isReady := readyStateMap[playerID]
 
 	// Compute ready count from server state (no client math) and gather confirmed names using lobby players
-	readyCount := 0
-	confirmedNames := make([]string, 0)
-	for id := range lobby.Players {
-		if readyStateMap[id] {
-			readyCount++
-			if p, ok := lobby.Players[id]; ok {
-				confirmedNames = append(confirmedNames, p.Name)
-			} else {
-				confirmedNames = append(confirmedNames, "unknown("+id+")")
+	readyCount := game.CountReadyPlayers(readyStateMap, lobby.Players)
+	totalPlayers := len(lobby.Players)
+
+	// Detailed logging for readiness change
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug("Readiness changed", "phase", statusBefore, "prev", prev, "now", isReady,
+			"confirmed", logging.Names(game.GetReadyPlayerNames(readyStateMap, lobby.Players)), "ready", readyCount, "players", totalPlayers)
+	}
+
+	// Report the count of the CURRENT (pre-advance) phase
+	update := &readyUpdate{status: statusBefore, isReady: isReady}
+	update.evs = append(update.evs, events.ReadyChanged{Phase: statusBefore, PlayerID: playerID, Ready: isReady, Count: readyCount, Total: totalPlayers})
+
+	// Advance AFTER preparing current-phase outputs
+	if game.ShouldAdvancePhase(readyCount, totalPlayers, statusBefore) {
+		switch statusBefore {
+		case models.StatusReadyCheck:
+			g.SetStatus(models.StatusRoleReveal)
+			// Pre-seed next phase readiness map
+			for id := range lobby.Players {
+				if _, ok := g.ReadyAfterReveal[id]; !ok {
+					g.ReadyAfterReveal[id] = false
+				}
+			}
+		case models.StatusRoleReveal:
+			g.SetStatus(models.StatusPlaying)
+			// Record when playing phase started (for timer sync)
+			g.PlayStartedAt = time.Now()
+			// Pre-seed next phase readiness map
+			for id := range lobby.Players {
+				if _, ok := g.ReadyToVote[id]; !ok {
+					g.ReadyToVote[id] = false
+				}
 			}
+			// Choose random first questioner
+			playerIDs := make([]string, 0, len(lobby.Players))
+			for id := range lobby.Players {
+				playerIDs = append(playerIDs, id)
+			}
+			g.FirstQuestioner = playerIDs[rand.Intn(len(playerIDs))]
+		case models.StatusPlaying:
+			g.SetStatus(models.StatusVoting)
 		}
+		update.nextPath = game.PhasePathFor(roomCode, g.Status)
+		update.evs = append(update.evs, phaseChanged(g))
 	}
-	totalPlayers := len(lobby.Players)
+	return update, nil
+}
 
-	// Actor name for logging
-	actorName := "unknown"
-	if p, ok := lobby.Players[playerID]; ok {
-		actorName = p.Name
+// phaseChanged describes the phase a game just entered (caller must hold lobby lock)
+func phaseChanged(g *models.Game) events.PhaseChanged {
+	ev := events.PhaseChanged{Phase: g.Status}
+	if g.Status == models.StatusVoting {
+		ev.VoteRound = g.VoteRound
 	}
+	return ev
+}
 
-	// Decide whether to advance based on the computed count
-	shouldAdvance := false
-	switch statusBefore {
-	case models.StatusReadyCheck, models.StatusRoleReveal:
-		shouldAdvance = readyCount == totalPlayers
-	case models.StatusPlaying:
-		shouldAdvance = readyCount > totalPlayers/2
-	}
+// broadcastReady publishes a readiness change and wakes up bots
+func (ctx *Context) broadcastReady(lobby *models.Lobby, roomCode, actorID string, update *readyUpdate) {
+	sse.Publish(lobby, update.evs...)
 
-	// Prepare outgoing UI for the CURRENT (pre-advance) phase
-	switch statusBefore {
-	case models.StatusReadyCheck:
-		readyCountMsg = ctx.ReadyCount(readyCount, len(lobby.Players), "players ready")
-		readyCountEventName = "ready-count-check"
-	case models.StatusRoleReveal:
-		readyCountMsg = ctx.ReadyCount(readyCount, len(lobby.Players), "players ready")
-		readyCountEventName = "ready-count-reveal"
-	case models.StatusPlaying:
-		readyCountMsg = ctx.ReadyCount(readyCount, len(lobby.Players), "players ready to vote")
-		readyCountEventName = "ready-count-playing"
+	if update.nextPath != "" {
+		ctx.scheduleBots(lobby, roomCode)
+	} else if !ctx.isBot(lobby, actorID) {
+		// Bots follow human readiness (e.g. ready to vote once someone else is)
+		ctx.scheduleBots(lobby, roomCode)
 	}
+}
 
+// readyButtonHTML renders the ready button for a phase
+func readyButtonHTML(status models.GameStatus, isReady bool) string {
 	buttonID := "ready-button-check"
 	buttonText := "I'm Ready to See My Role"
 	buttonClass := "btn btn-primary"
-	switch statusBefore {
+	switch status {
 	case models.StatusReadyCheck:
 		buttonID = "ready-button-check"
 		if isReady {
@@ -303,67 +350,7 @@ func (ctx *Context) gameHandleReadyCookie(w http.ResponseWriter, r *http.Request
 	bb.WriteString(`">`)
 	bb.WriteString(buttonText)
 	bb.WriteString(`</button>`)
-	buttonHTML = bb.String()
-
-	// Detailed logging for readiness change
-	if debug {
-		log.Printf("ready: room=%s phase=%s actor=%s(%s) prev=%v now=%v confirmed=[%s] count=%d/%d", roomCode, statusBefore, actorName, playerID, prev, isReady, strings.Join(confirmedNames, ", "), readyCount, totalPlayers)
-	}
-
-	// Advance AFTER preparing current-phase outputs
-	nextPath := ""
-	if shouldAdvance {
-		switch statusBefore {
-		case models.StatusReadyCheck:
-			g.Status = models.StatusRoleReveal
-			// Pre-seed next phase readiness map
-			for id := range lobby.Players {
-				if _, ok := g.ReadyAfterReveal[id]; !ok {
-					g.ReadyAfterReveal[id] = false
-				}
-			}
-			nextPath = game.PhasePathFor(roomCode, g.Status)
-			shouldBroadcastPhase = true
-		case models.StatusRoleReveal:
-			g.Status = models.StatusPlaying
-			// Record when playing phase started (for timer sync)
-			g.PlayStartedAt = time.Now()
-			// Pre-seed next phase readiness map
-			for id := range lobby.Players {
-				if _, ok := g.ReadyToVote[id]; !ok {
-					g.ReadyToVote[id] = false
-				}
-			}
-			// Choose random first questioner
-			playerIDs := make([]string, 0, len(lobby.Players))
-			for id := range lobby.Players {
-				playerIDs = append(playerIDs, id)
-			}
-			g.FirstQuestioner = playerIDs[rand.Intn(len(playerIDs))]
-			nextPath = game.PhasePathFor(roomCode, g.Status)
-			shouldBroadcastPhase = true
-		case models.StatusPlaying:
-			g.Status = models.StatusVoting
-			nextPath = game.PhasePathFor(roomCode, g.Status)
-			shouldBroadcastPhase = true
-		}
-	}
-	lobby.Unlock()
-
-	// Broadcast the server-derived current-phase count
-	sse.Broadcast(lobby, readyCountEventName, readyCountMsg)
-
-	// If phase advanced, instruct clients to navigate; no client-side math
-	if shouldBroadcastPhase {
-		sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, nextPath))
-		// Also ensure the initiating client navigates via HX-Redirect
-		w.Header().Set("HX-Redirect", nextPath)
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(buttonHTML))
+	return bb.String()
 }
 
 // gameHandleVoteCookie records a vote using cookie-based player ID
@@ -384,76 +371,116 @@ func (ctx *Context) gameHandleVoteCookie(w http.ResponseWriter, r *http.Request,
 	r.ParseForm()
 	suspectID := r.FormValue("suspect")
 
-	var voteCountMsg string
-	var shouldFinish bool
-	var shouldRevote bool
+	update, err := ctx.applyVote(lobby, roomCode, playerID, suspectID)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	ctx.broadcastVote(lobby, roomCode, update)
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(ctx.VotedConfirmation()))
+}
+
+// voteUpdate is the server-derived outcome of a vote
+type voteUpdate struct {
+	revote bool           // tie: a new voting round started
+	evs    []events.Event // published once the lobby lock is released
+}
+
+// applyVote records a vote and finishes the game or starts a revote once everyone has voted
+func (ctx *Context) applyVote(lobby *models.Lobby, roomCode, playerID, suspectID string) (*voteUpdate, error) {
+	var update *voteUpdate
+	err := ctx.updateLobby(lobby, func() (err error) {
+		update, err = ctx.voteChange(lobby, playerID, suspectID)
+		return err
+	})
+	return update, err
+}
 
+// voteChange is the change saved by applyVote
+func (ctx *Context) voteChange(lobby *models.Lobby, playerID, suspectID string) (*voteUpdate, error) {
 	lobby.Lock()
+	defer lobby.Unlock()
+
 	g := lobby.CurrentGame
 	if g == nil || g.Status != models.StatusVoting {
-		lobby.Unlock()
-		http.Error(w, "Not in voting phase", http.StatusBadRequest)
-		return
+		return nil, reject(http.StatusConflict, api.CodeWrongPhase, "Not in voting phase")
+	}
+	if _, ok := lobby.Players[suspectID]; !ok {
+		return nil, reject(http.StatusBadRequest, api.CodeBadRequest, "Unknown suspect")
 	}
 
 	g.Votes[playerID] = suspectID
+	update := &voteUpdate{}
+	update.evs = append(update.evs, events.VoteCast{Round: g.VoteRound, PlayerID: playerID, Count: len(g.Votes), Total: len(lobby.Players)})
 
 	if len(g.Votes) == len(lobby.Players) {
-		// Count votes
-		voteCount := make(map[string]int)
-		for _, votedFor := range g.Votes {
-			voteCount[votedFor]++
-		}
+		ctx.tallyVotes(lobby, update)
+	}
+	return update, nil
+}
 
-		maxVotes := 0
-		var playersWithMaxVotes []string
-		for pID, count := range voteCount {
-			if count > maxVotes {
-				maxVotes = count
-				playersWithMaxVotes = []string{pID}
-			} else if count == maxVotes {
-				playersWithMaxVotes = append(playersWithMaxVotes, pID)
-			}
-		}
+// tallyVotes closes a voting round everyone took part in: a tie starts a revote, otherwise the game finishes
+// Caller must hold lobby lock
+func (ctx *Context) tallyVotes(lobby *models.Lobby, update *voteUpdate) {
+	g := lobby.CurrentGame
+	if game.CloseVoteRound(g) {
+		update.evs = append(update.evs, ctx.finishGame(lobby))
+	} else {
+		update.revote = true
+		update.evs = append(update.evs, phaseChanged(g))
+	}
+}
 
-		if len(playersWithMaxVotes) > 1 && g.VoteRound < game.MaxVoteRounds {
-			// tie -> revote
-			g.Votes = make(map[string]string)
-			g.VoteRound++
-			shouldRevote = true
-		} else {
-			// finish game
-			g.Status = models.StatusFinished
-			innocentWon := len(playersWithMaxVotes) == 1 && playersWithMaxVotes[0] == g.SpyID
-			for id := range lobby.Players {
-				if id == g.SpyID {
-					if innocentWon {
-						lobby.Scores[id].GamesLost++
-					} else {
-						lobby.Scores[id].GamesWon++
-					}
-				} else {
-					if innocentWon {
-						lobby.Scores[id].GamesWon++
-					} else {
-						lobby.Scores[id].GamesLost++
-					}
-				}
-			}
-			shouldFinish = true
+// finishGame ends the current game: it freezes the result, applies the score changes and archives the game
+// It returns the event to publish once the lock is released
+// Set g.SpyForfeited before calling when the spy left
+// Caller must hold lobby lock
+func (ctx *Context) finishGame(lobby *models.Lobby) events.GameFinished {
+	g := lobby.CurrentGame
+	mode := ctx.Modes.Get(g.Mode)
+
+	g.Result = game.ComputeResult(g, mode, lobby.Players, lobby.Scores)
+	g.SetStatus(models.StatusFinished)
+
+	for id, delta := range g.Result.ScoreDeltas {
+		if score, ok := lobby.Scores[id]; ok {
+			score.GamesWon += delta.GamesWon
+			score.GamesLost += delta.GamesLost
+			score.Rating.Apply(mode.IsImpostor(g, id), g.Result.RatingDeltas[id])
 		}
 	}
+	archiveGame(lobby, mode)
+	outcome, innocentWon, outcomes := gameOutcome(g.Result), g.Result.InnocentWon, profileOutcomes(lobby, mode)
+	lobby.AfterSave(func() {
+		metrics.GameFinished(outcome)
+		ctx.running.Go(func() { ctx.recordProfileStats(innocentWon, outcomes) })
+	})
+
+	return events.GameFinished{
+		InnocentWon:  g.Result.InnocentWon,
+		SpyForfeited: g.Result.SpyForfeited,
+		MostVoted:    g.Result.MostVoted,
+		IsTie:        g.Result.IsTie,
+	}
+}
 
-	voteCountMsg = ctx.VoteCount(len(g.Votes), len(lobby.Players))
-	lobby.Unlock()
-
-	sse.Broadcast(lobby, sse.EventVoteCount, voteCountMsg)
-	if shouldRevote {
-		sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, models.StatusVoting)))
-	} else if shouldFinish {
-		sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, models.StatusFinished)))
+// gameOutcome maps a game result to its outcome metric
+func gameOutcome(result *models.GameResult) string {
+	switch {
+	case result.SpyForfeited:
+		return metrics.OutcomeForfeit
+	case result.InnocentWon:
+		return metrics.OutcomeInnocentWin
 	}
+	return metrics.OutcomeSpyWin
+}
 
-	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(ctx.VotedConfirmation()))
+// broadcastVote publishes a vote and wakes up bots on a revote
+func (ctx *Context) broadcastVote(lobby *models.Lobby, roomCode string, update *voteUpdate) {
+	sse.Publish(lobby, update.evs...)
+	if update.revote {
+		ctx.scheduleBots(lobby, roomCode)
+	}
 }
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+)
+
+// archiveGame appends an immutable record of the just-finished game to the lobby history
+// Reads the outcome from g.Result, so call it after the result is frozen
+// Caller must hold lobby lock
+func archiveGame(lobby *models.Lobby, mode modes.Mode) {
+	g := lobby.CurrentGame
+
+	rec := &models.GameRecord{
+		Number:         1,
+		Mode:           g.Mode,
+		Secrets:        mode.Secrets(g),
+		Result:         *g.Result,
+		PhaseStartedAt: maps.Clone(g.PhaseStartedAt),
+		FinishedAt:     time.Now(),
+	}
+	if n := len(lobby.History); n > 0 {
+		rec.Number = lobby.History[n-1].Number + 1
+	}
+
+	for _, round := range gameVoteRounds(g) {
+		rec.VoteRounds = append(rec.VoteRounds, maps.Clone(round))
+	}
+
+	for id, p := range lobby.Players {
+		rp := models.RecordPlayer{
+			ID:         id,
+			Name:       p.Name,
+			Role:       mode.RoleName(g, id),
+			Impostor:   mode.IsImpostor(g, id),
+			ScoreDelta: g.Result.ScoreDeltas[id],
+		}
+		if info, ok := g.PlayerInfo[id]; ok {
+			rp.Challenge = info.Challenge
+			rp.Word = info.Word
+		}
+		rec.Players = append(rec.Players, rp)
+	}
+	if g.SpyForfeited {
+		rec.Players = append(rec.Players, models.RecordPlayer{
+			ID:       g.SpyID,
+			Name:     g.SpyName,
+			Role:     mode.RoleName(g, g.SpyID),
+			Impostor: true,
+			Left:     true,
+		})
+	}
+	sort.Slice(rec.Players, func(i, j int) bool {
+		return strings.ToLower(rec.Players[i].Name) < strings.ToLower(rec.Players[j].Name)
+	})
+
+	lobby.History = append(lobby.History, rec)
+	if len(lobby.History) > game.MaxHistory {
+		lobby.History = lobby.History[len(lobby.History)-game.MaxHistory:]
+	}
+}
+
+// historyEntry is one row of the history list
+type historyEntry struct {
+	Number     int
+	ModeName   string
+	FinishedAt time.Time
+	Duration   string
+	Outcome    string
+	Impostors  string
+}
+
+// voteBallot is a single vote in a round of the results or drill-down view
+type voteBallot struct {
+	Voter   string
+	Suspect string
+	Correct bool
+}
+
+// historyPhase is a phase with its start time and duration
+type historyPhase struct {
+	Name     string
+	At       time.Time
+	Duration string
+}
+
+// recordPhases lists the phases of a record in order with their durations
+var recordPhases = []struct {
+	Status models.GameStatus
+	Name   string
+}{
+	{models.StatusReadyCheck, "Ready check"},
+	{models.StatusRoleReveal, "Role reveal"},
+	{models.StatusPlaying, "Discussion"},
+	{models.StatusVoting, "Voting"},
+	{models.StatusFinished, "Finished"},
+}
+
+// HandleHistory lists the finished games of a lobby (/history/{code}) or shows one game (/history/{code}/{n})
+func (ctx *Context) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/history/"), "/")
+	if len(parts) < 1 || len(parts) > 2 || parts[0] == "" {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	roomCode := parts[0]
+
+	lobby, playerID, err := ctx.getLobbyAndPlayer(r, roomCode)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	lobby.RLock()
+	defer lobby.RUnlock()
+
+	if len(parts) == 2 && parts[1] != "" {
+		number, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		for _, rec := range lobby.History {
+			if rec.Number == number {
+				ctx.renderHistoryGame(w, lobby, rec, playerID)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	// Newest first
+	entries := make([]historyEntry, 0, len(lobby.History))
+	for i := len(lobby.History) - 1; i >= 0; i-- {
+		rec := lobby.History[i]
+		entries = append(entries, historyEntry{
+			Number:     rec.Number,
+			ModeName:   ctx.Modes.Get(rec.Mode).Name(),
+			FinishedAt: rec.FinishedAt,
+			Duration:   recordDuration(rec),
+			Outcome:    recordOutcome(rec),
+			Impostors:  strings.Join(recordImpostors(rec), ", "),
+		})
+	}
+
+	data := struct {
+		RoomCode string
+		IsHost   bool
+		InGame   bool
+		Games    []historyEntry
+	}{
+		RoomCode: roomCode,
+		IsHost:   lobby.Host == playerID,
+		InGame:   lobby.CurrentGame != nil && lobby.CurrentGame.Status != models.StatusFinished,
+		Games:    entries,
+	}
+	ctx.Templates.ExecuteTemplate(w, "history.html", data)
+}
+
+// renderHistoryGame shows the drill-down view of one archived game
+// Caller must hold lobby lock
+func (ctx *Context) renderHistoryGame(w http.ResponseWriter, lobby *models.Lobby, rec *models.GameRecord, playerID string) {
+	names := make(map[string]string, len(rec.Players))
+	impostors := make(map[string]bool)
+	for _, p := range rec.Players {
+		names[p.ID] = p.Name
+		impostors[p.ID] = p.Impostor
+	}
+	nameOf := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return "(left)"
+	}
+
+	rounds := ballotRounds(rec.VoteRounds, nameOf, func(id string) bool { return impostors[id] })
+
+	var phases []historyPhase
+	for i, ph := range recordPhases {
+		at, ok := rec.PhaseStartedAt[ph.Status]
+		if !ok {
+			continue
+		}
+		hp := historyPhase{Name: ph.Name, At: at}
+		// Duration runs until the next recorded phase
+		for _, next := range recordPhases[i+1:] {
+			if nextAt, ok := rec.PhaseStartedAt[next.Status]; ok {
+				hp.Duration = formatDuration(nextAt.Sub(at))
+				break
+			}
+		}
+		phases = append(phases, hp)
+	}
+
+	data := struct {
+		RoomCode string
+		ModeName string
+		Game     *models.GameRecord
+		Outcome  string
+		VotedOut string
+		Rounds   [][]voteBallot
+		Phases   []historyPhase
+		PlayerID string
+	}{
+		RoomCode: lobby.Code,
+		ModeName: ctx.Modes.Get(rec.Mode).Name(),
+		Game:     rec,
+		Outcome:  recordOutcome(rec),
+		Rounds:   rounds,
+		Phases:   phases,
+		PlayerID: playerID,
+	}
+	if rec.Result.MostVoted != "" {
+		data.VotedOut = nameOf(rec.Result.MostVoted)
+	}
+	ctx.Templates.ExecuteTemplate(w, "history_game.html", data)
+}
+
+// gameVoteRounds returns the ballots of every round of a game, tie-break rounds first
+// Caller must hold lobby lock
+func gameVoteRounds(g *models.Game) []map[string]string {
+	rounds := slices.Clone(g.VoteHistory)
+	if len(g.Votes) > 0 {
+		rounds = append(rounds, g.Votes)
+	}
+	return rounds
+}
+
+// ballotRounds turns raw ballots into display rows, sorted by voter name within each round
+func ballotRounds(rounds []map[string]string, nameOf func(id string) string, impostor func(id string) bool) [][]voteBallot {
+	out := make([][]voteBallot, 0, len(rounds))
+	for _, votes := range rounds {
+		ballots := make([]voteBallot, 0, len(votes))
+		for voter, suspect := range votes {
+			ballots = append(ballots, voteBallot{Voter: nameOf(voter), Suspect: nameOf(suspect), Correct: impostor(suspect)})
+		}
+		sort.Slice(ballots, func(i, j int) bool { return strings.ToLower(ballots[i].Voter) < strings.ToLower(ballots[j].Voter) })
+		out = append(out, ballots)
+	}
+	return out
+}
+
+// recordImpostors returns the names of the hidden team with their roles
+func recordImpostors(rec *models.GameRecord) []string {
+	var names []string
+	for _, p := range rec.Players {
+		if p.Impostor {
+			names = append(names, fmt.Sprintf("%s (%s)", p.Name, p.Role))
+		}
+	}
+	return names
+}
+
+// recordOutcome describes who won an archived game, e.g. "Spy won (tie)"
+func recordOutcome(rec *models.GameRecord) string {
+	switch {
+	case rec.Result.SpyForfeited:
+		return "Innocents won (forfeit)"
+	case rec.Result.InnocentWon:
+		return "Innocents won"
+	}
+
+	var roles []string
+	for _, p := range rec.Players {
+		if p.Impostor && !slices.Contains(roles, p.Role) {
+			roles = append(roles, p.Role)
+		}
+	}
+	outcome := strings.Join(roles, " & ") + " won"
+	if rec.Result.IsTie {
+		outcome += " (tie)"
+	}
+	return outcome
+}
+
+// recordDuration is the time from the ready check to the end of the game
+func recordDuration(rec *models.GameRecord) string {
+	started, ok := rec.PhaseStartedAt[models.StatusReadyCheck]
+	if !ok {
+		return ""
+	}
+	return formatDuration(rec.FinishedAt.Sub(started))
+}
+
+// formatDuration renders a duration as e.g. "4m 05s"
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%dm %02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -2,22 +2,68 @@ package handlers
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
 	"github.com/aaronzipp/you-are-officially-sus/internal/render"
 	"github.com/aaronzipp/you-are-officially-sus/internal/store"
 )
 
 // Context holds shared application dependencies
 type Context struct {
-	LobbyStore *store.LobbyStore
+	LobbyStore store.LobbyStore
 	Templates  *template.Template
-	Locations  []models.Location
-	Challenges []string
+	Modes      *modes.Registry
+	Profiles   store.ProfileStore
 	BaseURL    string
+	AdminToken string // enables /admin when set
+
+	draining  atomic.Bool    // set by BeginShutdown; new lobbies are refused
+	notice    atomic.Value   // maintenance message set from /admin (string, empty when none)
+	profileMu sync.Mutex     // serializes recordProfileStats, see there
+	running   sync.WaitGroup // WebSocket handlers and profile updates, see Wait
+}
+
+// BeginShutdown stops the creation of new lobbies while the server drains
+func (ctx *Context) BeginShutdown() {
+	ctx.draining.Store(true)
+}
+
+// Wait blocks until open WebSocket handlers and pending profile updates are done, or timeout passes
+// WebSocket connections are hijacked, so server.Shutdown does not wait for them; the stores must
+// stay open until they end. It reports whether everything finished in time.
+func (ctx *Context) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		ctx.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Notice returns the current maintenance message ("" when none)
+func (ctx *Context) Notice() string {
+	message, _ := ctx.notice.Load().(string)
+	return message
+}
+
+// playerLogger returns the logger of a request, tagged with the lobby and player it acts on
+func playerLogger(r *http.Request, roomCode, playerID string) *slog.Logger {
+	return logging.From(r).With("lobby", roomCode, "player", playerID)
 }
 
 // ExecutePartial executes a template partial and returns the HTML string
@@ -25,7 +71,7 @@ func (ctx *Context) ExecutePartial(name string, data interface{}) string {
 	var buf bytes.Buffer
 	if err := ctx.Templates.ExecuteTemplate(&buf, name, data); err != nil {
 		// Log error to help debug template issues
-		log.Printf("ERROR: ExecutePartial failed for %s: %v (data type: %T)", name, err, data)
+		slog.Error("ExecutePartial failed", "template", name, "data_type", fmt.Sprintf("%T", data), "err", err)
 		return ""
 	}
 	return buf.String()
@@ -42,17 +88,59 @@ func (ctx *Context) PlayerList(players map[string]*models.Player) string {
 
 // HostControls generates HTML for host controls using template partials
 func (ctx *Context) HostControls(lobby *models.Lobby, playerID string) string {
-	return ctx.ExecutePartial("host_controls.html", struct {
-		IsHost      bool
-		PlayerCount int
-		InGame      bool
-		RoomCode    string
-	}{
+	return ctx.ExecutePartial("host_controls.html", ctx.hostControlsView(lobby, playerID))
+}
+
+// hostControlsView is the template data for host_controls.html
+type hostControlsView struct {
+	IsHost      bool
+	PlayerCount int
+	InGame      bool
+	RoomCode    string
+	ModeName    string
+	Modes       []modeChoice
+	Options     []optionChoice
+	Bots        []*models.Player
+	CanAddBot   bool
+}
+
+// modeChoice is an entry in the host's mode selector
+type modeChoice struct {
+	ID       models.GameMode
+	Name     string
+	Selected bool
+}
+
+// optionChoice is a mode-specific checkbox in the host controls
+type optionChoice struct {
+	Key     string
+	Label   string
+	Checked bool
+}
+
+// hostControlsView builds the data for the host controls partial (must be called with lock held)
+func (ctx *Context) hostControlsView(lobby *models.Lobby, playerID string) hostControlsView {
+	mode := ctx.Modes.Get(lobby.Mode)
+	view := hostControlsView{
 		IsHost:      lobby.Host == playerID,
 		PlayerCount: len(lobby.Players),
 		InGame:      lobby.CurrentGame != nil,
 		RoomCode:    lobby.Code,
-	})
+		ModeName:    mode.Name(),
+	}
+	for _, m := range ctx.Modes.All() {
+		view.Modes = append(view.Modes, modeChoice{ID: m.ID(), Name: m.Name(), Selected: m.ID() == mode.ID()})
+	}
+	for _, opt := range mode.Options() {
+		view.Options = append(view.Options, optionChoice{Key: opt.Key, Label: opt.Label, Checked: lobby.Options[opt.Key]})
+	}
+	for _, p := range render.GetPlayerList(lobby.Players) {
+		if p.IsBot {
+			view.Bots = append(view.Bots, p)
+		}
+	}
+	view.CanAddBot = len(view.Bots) < game.MaxBots
+	return view
 }
 
 // ScoreTable generates HTML for the score table using template partials
@@ -124,6 +212,16 @@ func (ctx *Context) GameAbortedMessage(reason string) string {
 	})
 }
 
+// ServerNotice generates HTML for a server-wide notice (an empty message clears it)
+// The cleared state still renders an element because EventSource drops events without data
+func (ctx *Context) ServerNotice(message string) string {
+	return ctx.ExecutePartial("server_notice.html", struct {
+		Message string
+	}{
+		Message: message,
+	})
+}
+
 // HostNotification generates HTML for new host notification
 func (ctx *Context) HostNotification() string {
 	return ctx.ExecutePartial("host_notification.html", nil)
@@ -135,5 +233,12 @@ func (ctx *Context) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	ctx.Templates.ExecuteTemplate(w, "index.html", nil)
+	// Suggest the profile name in the join and create forms
+	data := struct {
+		ProfileName string
+	}{}
+	if profile, ok := ctx.playerProfile(r); ok {
+		data.ProfileName = profile.Name
+	}
+	ctx.Templates.ExecuteTemplate(w, "index.html", data)
 }
@@ -1,21 +1,25 @@
 package handlers
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
 	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/metrics"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
 	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
 )
 
 // HandleStartGame starts a new game in the lobby
 func (ctx *Context) HandleStartGame(w http.ResponseWriter, r *http.Request) {
-	log.Printf("HandleStartGame called: %s %s", r.Method, r.URL.Path)
-
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -23,11 +27,8 @@ func (ctx *Context) HandleStartGame(w http.ResponseWriter, r *http.Request) {
 
 	roomCode := strings.TrimPrefix(r.URL.Path, "/start-game/")
 
-	log.Printf("HandleStartGame: roomCode=%s", roomCode)
-
 	lobby, exists := ctx.LobbyStore.Get(roomCode)
 	if !exists {
-		log.Printf("HandleStartGame: lobby %s not found", roomCode)
 		http.Error(w, "Lobby not found", http.StatusNotFound)
 		return
 	}
@@ -35,96 +36,89 @@ func (ctx *Context) HandleStartGame(w http.ResponseWriter, r *http.Request) {
 	// Get player ID from cookie
 	cookie, err := r.Cookie("player_id")
 	if err != nil {
-		log.Printf("HandleStartGame: no player_id cookie")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 	playerID := cookie.Value
+	logger := playerLogger(r, roomCode, playerID)
 
-	log.Printf("HandleStartGame: playerID=%s isHost=%v", playerID, lobby.Host == playerID)
-
-	lobby.Lock()
-
-	// Check if player is host
-	if lobby.Host != playerID {
-		lobby.Unlock()
-		log.Printf("HandleStartGame: player is not host")
-		http.Error(w, "Only host can start game", http.StatusForbidden)
+	if err := ctx.startGame(logger, lobby, playerID); err != nil {
+		logger.Debug("Start game rejected", "err", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	if lobby.CurrentGame != nil {
-		lobby.Unlock()
-		log.Printf("HandleStartGame: game already in progress")
-		http.Error(w, "Game already in progress", http.StatusBadRequest)
-		return
-	}
-
-	if len(lobby.Players) < game.MinPlayers {
-		lobby.Unlock()
-		log.Printf("HandleStartGame: not enough players (%d)", len(lobby.Players))
-		http.Error(w, "Need at least 3 players", http.StatusBadRequest)
-		return
-	}
+	w.Header().Set("HX-Redirect", game.PhasePathFor(roomCode, models.StatusReadyCheck))
+	w.WriteHeader(http.StatusOK)
+}
 
-	log.Printf("HandleStartGame: creating game for lobby %s", roomCode)
+// startGame deals a new game when the host asks for it
+func (ctx *Context) startGame(logger *slog.Logger, lobby *models.Lobby, playerID string) error {
+	roomCode := lobby.Code
+	var mode modes.Mode
+	var players int
+	err := ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+
+		// Check if player is host
+		if lobby.Host != playerID {
+			return reject(http.StatusForbidden, api.CodeForbidden, "Only host can start game")
+		}
 
-	// Create new game
-	newGame := &models.Game{
-		Location:         &ctx.Locations[rand.Intn(len(ctx.Locations))],
-		PlayerInfo:       make(map[string]*models.GamePlayerInfo),
-		Status:           models.StatusReadyCheck,
-		ReadyToReveal:    make(map[string]bool),
-		ReadyAfterReveal: make(map[string]bool),
-		ReadyToVote:      make(map[string]bool),
-		Votes:            make(map[string]string),
-		VoteRound:        1,
-	}
-	// Pre-seed current phase readiness map with all players
-	for id := range lobby.Players {
-		newGame.ReadyToReveal[id] = false
-	}
+		if lobby.CurrentGame != nil {
+			return reject(http.StatusConflict, api.CodeGameInProgress, "Game already in progress")
+		}
 
-	// Assign spy
-	playerIDs := make([]string, 0, len(lobby.Players))
-	for id := range lobby.Players {
-		playerIDs = append(playerIDs, id)
-	}
-	spyID := playerIDs[rand.Intn(len(playerIDs))]
-	newGame.SpyID = spyID
-	newGame.SpyName = lobby.Players[spyID].Name
+		if len(lobby.Players) < game.MinPlayers {
+			return reject(http.StatusBadRequest, api.CodeBadRequest, "Need at least 3 players")
+		}
 
-	// Assign challenges and roles
-	shuffledChallenges := make([]string, len(ctx.Challenges))
-	copy(shuffledChallenges, ctx.Challenges)
-	rand.Shuffle(len(shuffledChallenges), func(i, j int) {
-		shuffledChallenges[i], shuffledChallenges[j] = shuffledChallenges[j], shuffledChallenges[i]
-	})
+		mode = ctx.Modes.Get(lobby.Mode)
+
+		// Create new game
+		newGame := &models.Game{
+			Mode:             mode.ID(),
+			PlayerInfo:       make(map[string]*models.GamePlayerInfo),
+			ReadyToReveal:    make(map[string]bool),
+			ReadyAfterReveal: make(map[string]bool),
+			ReadyToVote:      make(map[string]bool),
+			Votes:            make(map[string]string),
+			VoteRound:        1,
+		}
+		newGame.SetStatus(models.StatusReadyCheck)
+		// Pre-seed current phase readiness map with all players
+		for id := range lobby.Players {
+			newGame.ReadyToReveal[id] = false
+		}
 
-	for i, id := range playerIDs {
-		newGame.PlayerInfo[id] = &models.GamePlayerInfo{
-			Challenge: shuffledChallenges[i%len(shuffledChallenges)],
-			IsSpy:     id == newGame.SpyID,
+		// Deal roles in random order; the mode decides who gets which secret
+		playerIDs := make([]string, 0, len(lobby.Players))
+		for id := range lobby.Players {
+			playerIDs = append(playerIDs, id)
 		}
+		rand.Shuffle(len(playerIDs), func(i, j int) {
+			playerIDs[i], playerIDs[j] = playerIDs[j], playerIDs[i]
+		})
+		mode.Setup(newGame, lobby, playerIDs)
+
+		lobby.CurrentGame = newGame
+		players = len(playerIDs)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	lobby.CurrentGame = newGame
-	lobby.Unlock()
-
-	log.Printf("HandleStartGame: game created, broadcasting redirect to confirm-reveal")
-
-	// Broadcast HTMX redirect snippet to all clients to go to confirm-reveal
-	sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, models.StatusReadyCheck)))
+	logger.Info("Game started", "mode", mode.ID(), "players", players)
 
-	log.Printf("HandleStartGame: complete")
-	w.Header().Set("HX-Redirect", game.PhasePathFor(roomCode, models.StatusReadyCheck))
-	w.WriteHeader(http.StatusOK)
+	sse.Publish(lobby, events.PhaseChanged{Phase: models.StatusReadyCheck})
+	ctx.scheduleBots(lobby, roomCode)
+	return nil
 }
 
 // HandleRestartGame resets the game and returns to lobby
 func (ctx *Context) HandleRestartGame(w http.ResponseWriter, r *http.Request) {
-	log.Printf("HandleRestartGame called: %s %s", r.Method, r.URL.Path)
-
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -141,35 +135,49 @@ func (ctx *Context) HandleRestartGame(w http.ResponseWriter, r *http.Request) {
 	// Get player ID from cookie
 	cookie, err := r.Cookie("player_id")
 	if err != nil {
-		log.Printf("HandleRestartGame: no player_id cookie")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 	playerID := cookie.Value
+	logger := playerLogger(r, roomCode, playerID)
 
-	lobby.Lock()
-
-	// Check if player is host
-	if lobby.Host != playerID {
-		lobby.Unlock()
-		log.Printf("HandleRestartGame: player %s is not host", playerID)
-		http.Error(w, "Only host can restart game", http.StatusForbidden)
+	if err := ctx.restartGame(logger, lobby, playerID); err != nil {
+		logger.Debug("Restart rejected", "err", err)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	// Clear game
-	lobby.CurrentGame = nil
+	w.Header().Set("HX-Redirect", "/lobby/"+roomCode)
+	w.WriteHeader(http.StatusOK)
+}
 
-	lobby.Unlock()
+// restartGame drops the current game and sends everyone back to the lobby
+func (ctx *Context) restartGame(logger *slog.Logger, lobby *models.Lobby, playerID string) error {
+	err := ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
 
-	log.Printf("HandleRestartGame: game cleared, broadcasting nav-redirect to lobby")
+		// Check if player is host
+		if lobby.Host != playerID {
+			return reject(http.StatusForbidden, api.CodeForbidden, "Only host can restart game")
+		}
 
-	// Broadcast restart WITHOUT holding lock
-	sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, "/lobby/"+roomCode))
+		// Clear game, abandoning it if it was still running
+		if g := lobby.CurrentGame; g != nil && g.Status != models.StatusFinished {
+			lobby.AfterSave(func() { metrics.GameFinished(metrics.OutcomeAbort) })
+		}
+		lobby.CurrentGame = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-	log.Printf("HandleRestartGame: sending redirect response")
-	w.Header().Set("HX-Redirect", "/lobby/"+roomCode)
-	w.WriteHeader(http.StatusOK)
+	logger.Info("Game cleared, sending everyone to the lobby")
+
+	// Publish WITHOUT holding lock
+	sse.Publish(lobby, events.PhaseChanged{Phase: models.StatusWaiting})
+	return nil
 }
 
 // HandleCloseLobby deletes the lobby
@@ -203,16 +211,32 @@ func (ctx *Context) HandleCloseLobby(w http.ResponseWriter, r *http.Request) {
 	}
 	lobby.Unlock()
 
-	// Broadcast closure
-	sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, "/"))
-
-	// Delete lobby
-	ctx.LobbyStore.Delete(roomCode)
+	playerLogger(r, roomCode, playerID).Info("Host closed lobby")
+	ctx.closeLobby(roomCode, lobby, "closed")
 
 	w.Header().Set("HX-Redirect", "/")
 	w.WriteHeader(http.StatusOK)
 }
 
+// closeLobby sends every client of the lobby home and deletes it
+func (ctx *Context) closeLobby(roomCode string, lobby *models.Lobby, reason string) {
+	lobby.RLock()
+	if g := lobby.CurrentGame; g != nil && g.Status != models.StatusFinished {
+		metrics.GameFinished(metrics.OutcomeAbort)
+	}
+	lobby.RUnlock()
+	sse.Publish(lobby, events.LobbyClosed{Reason: reason})
+	ctx.LobbyStore.Delete(roomCode)
+}
+
+// ExpireLobby notifies clients of a lobby removed by the idle janitor
+// The janitor only expires lobbies without connections, so this reaches clients that raced in
+func (ctx *Context) ExpireLobby(roomCode string, lobby *models.Lobby) {
+	slog.Info("Lobby expired", "lobby", roomCode)
+	metrics.LobbyExpired()
+	sse.Publish(lobby, events.LobbyClosed{Reason: "expired"})
+}
+
 // HandleLeaveLobby allows a player to leave the lobby/game
 func (ctx *Context) HandleLeaveLobby(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -238,11 +262,11 @@ func (ctx *Context) HandleLeaveLobby(w http.ResponseWriter, r *http.Request) {
 
 	lobby.RLock()
 	isHost := lobby.Host == playerID
-	playerCount := len(lobby.Players)
+	humanCount := lobby.HumanCount()
 	lobby.RUnlock()
 
-	// If host and there are other players, redirect to host selection page
-	if isHost && playerCount > 1 {
+	// If host and there are other (human) players, redirect to host selection page
+	if isHost && humanCount > 1 {
 		w.Header().Set("HX-Redirect", "/select-host/"+roomCode)
 		w.WriteHeader(http.StatusOK)
 		return
@@ -278,14 +302,14 @@ func (ctx *Context) HandleSelectHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get other players (excluding current host)
+	// Get other players (excluding current host and bots)
 	type Player struct {
 		ID   string
 		Name string
 	}
 	otherPlayers := []Player{}
 	for id, player := range lobby.Players {
-		if id != playerID {
+		if id != playerID && !player.IsBot {
 			otherPlayers = append(otherPlayers, Player{ID: id, Name: player.Name})
 		}
 	}
@@ -342,188 +366,162 @@ func (ctx *Context) HandleLeaveLobbyWithHost(w http.ResponseWriter, r *http.Requ
 // handleLeaveLogic contains the shared logic for leaving a lobby
 // If newHostID is provided, it will be used instead of auto-assignment
 func (ctx *Context) handleLeaveLogic(w http.ResponseWriter, r *http.Request, roomCode, playerID, newHostID string) {
-	lobby, exists := ctx.LobbyStore.Get(roomCode)
-	if !exists {
-		http.Error(w, "Lobby not found", http.StatusNotFound)
+	if err := ctx.leaveLobby(playerLogger(r, roomCode, playerID), roomCode, playerID, newHostID); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	lobby.Lock()
+	// Redirect leaving player to home
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
 
-	// Check if player is in lobby
-	player, exists := lobby.Players[playerID]
+// leaveLobby removes a player from a lobby, ending or adjusting a game in progress
+// The lobby is deleted when its last human leaves
+func (ctx *Context) leaveLobby(logger *slog.Logger, roomCode, playerID, newHostID string) error {
+	return ctx.removePlayer(logger, roomCode, playerID, newHostID, false)
+}
+
+// kickPlayer removes a player on behalf of an operator; their pages are sent home
+func (ctx *Context) kickPlayer(logger *slog.Logger, roomCode, playerID string) error {
+	return ctx.removePlayer(logger, roomCode, playerID, "", true)
+}
+
+// removePlayer is the shared core of leaveLobby and kickPlayer
+// logger carries the lobby and the player being removed
+func (ctx *Context) removePlayer(logger *slog.Logger, roomCode, playerID, newHostID string, kicked bool) error {
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
 	if !exists {
-		lobby.Unlock()
-		http.Error(w, "Player not in lobby", http.StatusBadRequest)
-		return
+		return reject(http.StatusNotFound, api.CodeNotFound, "Lobby not found")
 	}
 
-	wasHost := lobby.Host == playerID
-	playerName := player.Name
+	// Collect what changed while holding the lock; it is published once the change is saved
+	var evs []events.Event
+	var gameAborted, phaseAdvanced, lastAborted bool
+	err := ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		evs, gameAborted, phaseAdvanced, lastAborted = nil, false, false, false
 
-	log.Printf("Player leaving: code=%s playerID=%s name=%s wasHost=%v", roomCode, playerID, playerName, wasHost)
+		// Check if player is in lobby
+		player, exists := lobby.Players[playerID]
+		if !exists {
+			return reject(http.StatusBadRequest, api.CodeBadRequest, "Player not in lobby")
+		}
+		if newHostID != "" && lobby.Host == playerID {
+			if p, ok := lobby.Players[newHostID]; !ok || p.IsBot || newHostID == playerID {
+				return reject(http.StatusBadRequest, api.CodeBadRequest, "New host must be another player in the lobby")
+			}
+		}
 
-	// Remove player from lobby
-	delete(lobby.Players, playerID)
-	delete(lobby.Scores, playerID)
+		wasHost := lobby.Host == playerID
+		playerName := player.Name
 
-	// Check if this was the last player
-	if len(lobby.Players) == 0 {
-		lobby.Unlock()
-		log.Printf("Last player left, deleting lobby: code=%s", roomCode)
-		ctx.LobbyStore.Delete(roomCode)
-		w.Header().Set("HX-Redirect", "/")
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	// Reassign host if necessary
-	assignedHostID := ""
-	autoAssigned := false
-	if wasHost {
-		if newHostID != "" {
-			// Use the provided host ID (manual selection)
-			lobby.Host = newHostID
-			assignedHostID = newHostID
-			log.Printf("Host manually assigned: code=%s newHost=%s", roomCode, newHostID)
+		if kicked {
+			logger.Info("Player kicked", "name", logging.Name(playerName), "was_host", wasHost)
 		} else {
-			// Auto-assign new host
-			assignNewHost(lobby)
-			assignedHostID = lobby.Host
-			autoAssigned = true
-			log.Printf("Host auto-assigned: code=%s newHost=%s", roomCode, assignedHostID)
+			logger.Info("Player leaving", "name", logging.Name(playerName), "was_host", wasHost)
 		}
-	}
 
-	// Handle game state if game is in progress
-	gameEnded := false
-	innocentsWon := false
-	phaseAdvanced := false
-	if lobby.CurrentGame != nil {
-		g := lobby.CurrentGame
+		// Remove player from lobby
+		delete(lobby.Players, playerID)
+		delete(lobby.Scores, playerID)
+		revokeTokens(lobby, playerID)
 
-		// Check if spy left
-		spyLeft := g.SpyID == playerID
-
-		// Remove player from game state
-		removePlayerFromGame(g, playerID)
-
-		// Check if game should end
-		if spyLeft {
-			// Spy left - innocents win
-			log.Printf("Spy left the game: code=%s spyName=%s", roomCode, g.SpyName)
-			g.Status = models.StatusFinished
-			g.SpyForfeited = true
-			innocentsWon = true
-			gameEnded = true
+		// Check if this was the last player (bots can't keep a lobby alive)
+		if lobby.HumanCount() == 0 {
+			lastAborted = lobby.CurrentGame != nil && lobby.CurrentGame.Status != models.StatusFinished
+			return errLobbyEmpty
+		}
 
-			// Update scores for remaining players (they all win)
-			for id := range lobby.Players {
-				lobby.Scores[id].GamesWon++
+		evs = append(evs, events.PlayerLeft{PlayerID: playerID, Name: playerName, Bot: player.IsBot, Kicked: kicked})
+
+		// Reassign host if necessary
+		if wasHost {
+			if newHostID != "" {
+				// Use the provided host ID (manual selection)
+				lobby.Host = newHostID
+				logger.Info("Host manually assigned", "new_host", newHostID)
+				evs = append(evs, events.HostChanged{HostID: newHostID})
+			} else {
+				// Auto-assign new host
+				assignNewHost(lobby)
+				logger.Info("Host auto-assigned", "new_host", lobby.Host)
+				evs = append(evs, events.HostChanged{HostID: lobby.Host, Automatic: true})
 			}
-		} else if len(lobby.Players) < game.MinPlayers {
-			// Too few players - end game
-			log.Printf("Too few players remaining: code=%s count=%d", roomCode, len(lobby.Players))
-			lobby.CurrentGame = nil
-			gameEnded = true
-		} else {
-			// Game continues - check if phase should advance now that player is removed
-			phaseAdvanced = checkAndAdvancePhase(ctx, lobby, roomCode)
 		}
-	}
-
-	lobby.Unlock()
-
-	// Send notification to new host if host was auto-assigned (not manually selected)
-	if assignedHostID != "" && autoAssigned {
-		hostNotification := ctx.HostNotification()
-		sse.BroadcastToPlayer(lobby, assignedHostID, sse.EventHostChanged, hostNotification)
-	}
 
-	// Broadcast updates to remaining players
-	if gameEnded {
-		if innocentsWon {
-			// Redirect to results page
-			sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, models.StatusFinished)))
-		} else {
-			// Game cancelled due to insufficient players - show warning then redirect
-			abortMsg := ctx.GameAbortedMessage("Not enough players remaining (minimum 3 required)")
-			sse.Broadcast(lobby, sse.EventErrorMessage, abortMsg)
-
-			// Wait a moment, then redirect to lobby
-			go func() {
-				time.Sleep(3 * time.Second)
-				sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, "/lobby/"+roomCode))
-			}()
-		}
-	} else {
-		// Update player list and scores
-		sse.Broadcast(lobby, sse.EventPlayerUpdate, ctx.PlayerList(lobby.Players))
-		sse.Broadcast(lobby, sse.EventScoreUpdate, ctx.ScoreTable(lobby))
-		sse.BroadcastPersonalized(lobby, func(pid string) string {
-			return ctx.HostControls(lobby, pid)
-		}, sse.EventControlsUpdate)
-
-		// If phase advanced, redirect all players to new phase
-		if phaseAdvanced {
-			lobby.RLock()
-			newPhase := lobby.CurrentGame.Status
-			lobby.RUnlock()
-			nextPath := game.PhasePathFor(roomCode, newPhase)
-			log.Printf("Broadcasting phase transition after player leave: code=%s path=%s", roomCode, nextPath)
-			sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, nextPath))
-		} else if lobby.CurrentGame != nil {
-			// Update ready/vote counts if in game and phase didn't advance
-			lobby.RLock()
-			g := lobby.CurrentGame
-			switch g.Status {
-			case models.StatusReadyCheck:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyToReveal[id] {
-						readyCount++
-					}
-				}
-				lobby.RUnlock()
-				sse.Broadcast(lobby, "ready-count-check", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready"))
-			case models.StatusRoleReveal:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyAfterReveal[id] {
-						readyCount++
-					}
-				}
-				lobby.RUnlock()
-				sse.Broadcast(lobby, "ready-count-reveal", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready"))
-			case models.StatusPlaying:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyToVote[id] {
-						readyCount++
-					}
-				}
-				lobby.RUnlock()
-				sse.Broadcast(lobby, "ready-count-playing", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready to vote"))
-			case models.StatusVoting:
-				voteCount := len(g.Votes)
-				lobby.RUnlock()
-				sse.Broadcast(lobby, "vote-count-voting", ctx.VoteCount(voteCount, len(lobby.Players)))
-			default:
-				lobby.RUnlock()
+		// Handle game state if game is in progress (a finished game keeps its players for the results)
+		if g := lobby.CurrentGame; g != nil && g.Status != models.StatusFinished {
+			// Check if spy left
+			mode := ctx.Modes.Get(g.Mode)
+			spyLeft := mode.Forfeits(g, playerID)
+
+			// Remove player from game state
+			removePlayerFromGame(g, playerID)
+
+			// Check if game should end
+			if spyLeft {
+				// Spy left - innocents win
+				logger.Info("Spy left the game")
+				g.SpyForfeited = true
+
+				// Score remaining players (innocents win, any remaining Mr. White loses)
+				evs = append(evs, ctx.finishGame(lobby))
+			} else if len(lobby.Players) < game.MinPlayers {
+				// Too few players - end game
+				logger.Info("Too few players remaining, game aborted", "players", len(lobby.Players))
+				lobby.CurrentGame = nil
+				gameAborted = true
+				lobby.AfterSave(func() { metrics.GameFinished(metrics.OutcomeAbort) })
+				evs = append(evs, events.GameAborted{Reason: notEnoughPlayersReason})
+			} else if advanced := checkAndAdvancePhase(ctx, logger, lobby); len(advanced) > 0 {
+				// Game moves on now that the player is removed (bots only act while it is running)
+				phaseAdvanced = g.Status != models.StatusFinished
+				evs = append(evs, advanced...)
+			} else if ev := phaseCount(lobby); ev != nil {
+				// Game continues - update the ready/vote count
+				evs = append(evs, ev)
 			}
 		}
+		return nil
+	})
+	if errors.Is(err, errLobbyEmpty) {
+		if lastAborted {
+			metrics.GameFinished(metrics.OutcomeAbort)
+		}
+		logger.Info("Last player left, deleting lobby")
+		if kicked {
+			// Nobody else is left to keep the lobby, so the kicked player's pages are sent home this way
+			sse.Publish(lobby, events.LobbyClosed{Reason: "admin"})
+		}
+		ctx.LobbyStore.Delete(roomCode)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	// Redirect leaving player to home
-	w.Header().Set("HX-Redirect", "/")
-	w.WriteHeader(http.StatusOK)
+	sse.Publish(lobby, evs...)
+	if gameAborted {
+		ctx.returnToLobbySoon(lobby)
+	} else if phaseAdvanced {
+		ctx.scheduleBots(lobby, roomCode)
+	}
+	return nil
 }
 
-// assignNewHost assigns a new host to the lobby (first player by ID)
+// errLobbyEmpty stops a change that removed the last human player; the lobby is deleted instead of saved
+var errLobbyEmpty = errors.New("no players left")
+
+// assignNewHost assigns a new host to the lobby (first human player by ID)
 func assignNewHost(lobby *models.Lobby) {
 	// Find first player by ID (deterministic)
 	var firstID string
-	for id := range lobby.Players {
+	for id, p := range lobby.Players {
+		if p.IsBot {
+			continue
+		}
 		if firstID == "" || id < firstID {
 			firstID = id
 		}
@@ -546,11 +544,11 @@ func removePlayerFromGame(g *models.Game, playerID string) {
 }
 
 // checkAndAdvancePhase checks if the game should advance to the next phase after a player leaves
-// Returns true if phase advanced, false otherwise
+// Returns the events of the advance (a new phase, a revote or the end of the game), none if the game stays put
 // Caller must hold lobby lock
-func checkAndAdvancePhase(ctx *Context, lobby *models.Lobby, roomCode string) bool {
+func checkAndAdvancePhase(ctx *Context, logger *slog.Logger, lobby *models.Lobby) []events.Event {
 	if lobby.CurrentGame == nil {
-		return false
+		return nil
 	}
 
 	g := lobby.CurrentGame
@@ -567,8 +565,8 @@ func checkAndAdvancePhase(ctx *Context, lobby *models.Lobby, roomCode string) bo
 		}
 		shouldAdvance = readyCount == totalPlayers
 		if shouldAdvance {
-			log.Printf("Phase advancement after player leave: code=%s phase=%s->%s readyCount=%d/%d", roomCode, g.Status, models.StatusRoleReveal, readyCount, totalPlayers)
-			g.Status = models.StatusRoleReveal
+			logger.Info("Phase advanced after player left", "from", g.Status, "to", models.StatusRoleReveal, "ready", readyCount, "players", totalPlayers)
+			g.SetStatus(models.StatusRoleReveal)
 			// Pre-seed next phase readiness map
 			for id := range lobby.Players {
 				if _, ok := g.ReadyAfterReveal[id]; !ok {
@@ -586,8 +584,8 @@ func checkAndAdvancePhase(ctx *Context, lobby *models.Lobby, roomCode string) bo
 		}
 		shouldAdvance = readyCount == totalPlayers
 		if shouldAdvance {
-			log.Printf("Phase advancement after player leave: code=%s phase=%s->%s readyCount=%d/%d", roomCode, g.Status, models.StatusPlaying, readyCount, totalPlayers)
-			g.Status = models.StatusPlaying
+			logger.Info("Phase advanced after player left", "from", g.Status, "to", models.StatusPlaying, "ready", readyCount, "players", totalPlayers)
+			g.SetStatus(models.StatusPlaying)
 			// Record when playing phase started
 			g.PlayStartedAt = time.Now()
 			// Pre-seed next phase readiness map
@@ -615,161 +613,52 @@ func checkAndAdvancePhase(ctx *Context, lobby *models.Lobby, roomCode string) bo
 		}
 		shouldAdvance = readyCount > totalPlayers/2
 		if shouldAdvance {
-			log.Printf("Phase advancement after player leave: code=%s phase=%s->%s readyCount=%d/%d", roomCode, g.Status, models.StatusVoting, readyCount, totalPlayers)
-			g.Status = models.StatusVoting
+			logger.Info("Phase advanced after player left", "from", g.Status, "to", models.StatusVoting, "ready", readyCount, "players", totalPlayers)
+			g.SetStatus(models.StatusVoting)
 		}
 
 	case models.StatusVoting:
 		voteCount := len(g.Votes)
-		shouldAdvance = voteCount == totalPlayers
-		if shouldAdvance {
-			log.Printf("All votes collected after player leave: code=%s votes=%d/%d", roomCode, voteCount, totalPlayers)
-			// Vote calculation is handled separately in gameHandleVoteCookie
-			// Here we just note that all votes are in
+		if voteCount == totalPlayers {
+			// The remaining ballots are complete: tally them as the last vote would have
+			logger.Info("All votes collected after player left", "votes", voteCount, "players", totalPlayers)
+			update := &voteUpdate{}
+			ctx.tallyVotes(lobby, update)
+			return update.evs
 		}
 	}
 
-	return shouldAdvance
-}
-
-// handlePlayerDisconnect is called when a player's SSE connection is lost (browser closed/refreshed)
-// This handles automatic cleanup without the player explicitly clicking "Leave"
-func (ctx *Context) handlePlayerDisconnect(roomCode, playerID string) {
-	lobby, exists := ctx.LobbyStore.Get(roomCode)
-	if !exists {
-		return
+	if !shouldAdvance {
+		return nil
 	}
+	return []events.Event{phaseChanged(g)}
+}
 
-	lobby.Lock()
-
-	// Check if player is still in lobby
-	player, exists := lobby.Players[playerID]
-	if !exists {
-		lobby.Unlock()
-		return
-	}
-
-	wasHost := lobby.Host == playerID
-	playerName := player.Name
-
-	log.Printf("Player disconnected: code=%s playerID=%s name=%s wasHost=%v", roomCode, playerID, playerName, wasHost)
-
-	// Remove player from lobby
-	delete(lobby.Players, playerID)
-	delete(lobby.Scores, playerID)
-
-	// Check if this was the last player
-	if len(lobby.Players) == 0 {
-		lobby.Unlock()
-		log.Printf("Last player disconnected, deleting lobby: code=%s", roomCode)
-		ctx.LobbyStore.Delete(roomCode)
-		return
-	}
-
-	// Reassign host if necessary (auto-assign on disconnect)
-	newHostID := ""
-	if wasHost {
-		assignNewHost(lobby)
-		newHostID = lobby.Host
-		log.Printf("Host disconnected, reassigned: code=%s newHost=%s", roomCode, newHostID)
-	}
-
-	// Handle game state if game is in progress
-	gameEnded := false
-	innocentsWon := false
-	if lobby.CurrentGame != nil {
-		g := lobby.CurrentGame
-
-		// Check if spy disconnected
-		spyLeft := g.SpyID == playerID
-
-		// Remove player from game state
-		removePlayerFromGame(g, playerID)
+// notEnoughPlayersReason is shown when a game is aborted because players left
+const notEnoughPlayersReason = "Not enough players remaining (minimum 3 required)"
 
-		// Check if game should end
-		if spyLeft {
-			// Spy left - innocents win
-			log.Printf("Spy disconnected from game: code=%s spyName=%s", roomCode, g.SpyName)
-			g.Status = models.StatusFinished
-			g.SpyForfeited = true
-			innocentsWon = true
-			gameEnded = true
+// returnToLobbySoon sends everyone back to the lobby after they had a moment to read the abort message
+func (ctx *Context) returnToLobbySoon(lobby *models.Lobby) {
+	go func() {
+		time.Sleep(3 * time.Second)
+		sse.Publish(lobby, events.PhaseChanged{Phase: models.StatusWaiting})
+	}()
+}
 
-			// Update scores for remaining players (they all win)
-			for id := range lobby.Players {
-				lobby.Scores[id].GamesWon++
-			}
-		} else if len(lobby.Players) < game.MinPlayers {
-			// Too few players - end game
-			log.Printf("Too few players remaining after disconnect: code=%s count=%d", roomCode, len(lobby.Players))
-			lobby.CurrentGame = nil
-			gameEnded = true
-		}
+// phaseCount describes the ready or vote count of the current game phase (nil when the phase has none)
+// Caller must hold lobby lock
+func phaseCount(lobby *models.Lobby) events.Event {
+	g := lobby.CurrentGame
+	if g == nil {
+		return nil
 	}
-
-	lobby.Unlock()
-
-	// Send notification to new host if host changed
-	if newHostID != "" {
-		hostNotification := ctx.HostNotification()
-		sse.BroadcastToPlayer(lobby, newHostID, sse.EventHostChanged, hostNotification)
+	total := len(lobby.Players)
+	if g.Status == models.StatusVoting {
+		return events.VoteCast{Round: g.VoteRound, Count: len(g.Votes), Total: total}
 	}
-
-	// Broadcast updates to remaining players
-	if gameEnded {
-		if innocentsWon {
-			// Redirect to results page
-			sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, models.StatusFinished)))
-		} else {
-			// Game cancelled due to insufficient players - show warning then redirect
-			abortMsg := ctx.GameAbortedMessage("Not enough players remaining (minimum 3 required)")
-			sse.Broadcast(lobby, sse.EventErrorMessage, abortMsg)
-
-			// Wait a moment, then redirect to lobby
-			go func() {
-				time.Sleep(3 * time.Second)
-				sse.Broadcast(lobby, sse.EventNavRedirect, ctx.RedirectSnippet(roomCode, "/lobby/"+roomCode))
-			}()
-		}
-	} else {
-		// Update player list and scores
-		sse.Broadcast(lobby, sse.EventPlayerUpdate, ctx.PlayerList(lobby.Players))
-		sse.Broadcast(lobby, sse.EventScoreUpdate, ctx.ScoreTable(lobby))
-		sse.BroadcastPersonalized(lobby, func(pid string) string {
-			return ctx.HostControls(lobby, pid)
-		}, sse.EventControlsUpdate)
-
-		// Update ready/vote counts if in game
-		if lobby.CurrentGame != nil {
-			g := lobby.CurrentGame
-			switch g.Status {
-			case models.StatusReadyCheck:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyToReveal[id] {
-						readyCount++
-					}
-				}
-				sse.Broadcast(lobby, "ready-count-check", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready"))
-			case models.StatusRoleReveal:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyAfterReveal[id] {
-						readyCount++
-					}
-				}
-				sse.Broadcast(lobby, "ready-count-reveal", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready"))
-			case models.StatusPlaying:
-				readyCount := 0
-				for id := range lobby.Players {
-					if g.ReadyToVote[id] {
-						readyCount++
-					}
-				}
-				sse.Broadcast(lobby, "ready-count-playing", ctx.ReadyCount(readyCount, len(lobby.Players), "players ready to vote"))
-			case models.StatusVoting:
-				sse.Broadcast(lobby, "vote-count-voting", ctx.VoteCount(len(g.Votes), len(lobby.Players)))
-			}
-		}
+	ready := game.GetReadyStateMap(g)
+	if ready == nil {
+		return nil
 	}
+	return events.ReadyChanged{Phase: g.Status, Count: game.CountReadyPlayers(ready, lobby.Players), Total: total}
 }
@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
 	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
 	"github.com/aaronzipp/you-are-officially-sus/internal/render"
 	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
@@ -30,35 +36,54 @@ func (ctx *Context) HandleCreateLobby(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playerID := uuid.New().String()
-	roomCode := game.GetUniqueRoomCode(ctx.LobbyStore)
+	if ctx.draining.Load() {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("HX-Retarget", "#create-error")
+		w.Header().Set("HX-Reswap", "outerHTML")
+		w.WriteHeader(http.StatusOK) // htmx only swaps successful responses
+		w.Write([]byte(ctx.ErrorMessage("The server is restarting. Please try again in a moment.")))
+		return
+	}
+
+	// Keep an existing player_id so a linked profile follows the host into the new lobby
+	playerID := ensurePlayerID(w, r)
+	lobby, err := ctx.createLobby(logging.From(r).With("player", playerID), playerID, hostName)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	// Redirect to lobby
+	w.Header().Set("HX-Redirect", "/lobby/"+lobby.Code)
+	w.WriteHeader(http.StatusOK)
+}
 
-	lobby := &models.Lobby{
-		Code:    roomCode,
-		Host:    playerID,
-		Players: make(map[string]*models.Player),
-		Scores:  make(map[string]*models.PlayerScore),
+// createLobby creates a lobby hosted by playerID
+func (ctx *Context) createLobby(logger *slog.Logger, playerID, hostName string) (*models.Lobby, error) {
+	if hostName == "" {
+		return nil, reject(http.StatusBadRequest, api.CodeBadRequest, "Name is required")
+	}
+	if ctx.draining.Load() {
+		return nil, reject(http.StatusServiceUnavailable, api.CodeUnavailable, "The server is restarting. Please try again in a moment.")
 	}
+
+	roomCode := game.GetUniqueRoomCode(ctx.LobbyStore)
+	lobby := &models.Lobby{LobbyState: models.LobbyState{
+		Code:      roomCode,
+		Host:      playerID,
+		Players:   make(map[string]*models.Player),
+		Scores:    make(map[string]*models.PlayerScore),
+		Mode:      ctx.Modes.Default().ID(),
+		Options:   make(map[string]bool),
+		CreatedAt: time.Now(),
+	}}
 	lobby.Players[playerID] = &models.Player{ID: playerID, Name: hostName}
 	lobby.Scores[playerID] = &models.PlayerScore{}
 
 	ctx.LobbyStore.Set(roomCode, lobby)
 
-	log.Printf("Created lobby: code=%s host=%s", roomCode, playerID)
-
-	// Set cookie for player ID (session)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "player_id",
-		Value:    playerID,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		// Secure: true, // enable when serving over HTTPS
-	})
-
-	// Redirect to lobby
-	w.Header().Set("HX-Redirect", "/lobby/"+roomCode)
-	w.WriteHeader(http.StatusOK)
+	logger.Info("Created lobby", "lobby", roomCode, "name", logging.Name(hostName))
+	return lobby, nil
 }
 
 // HandleJoinLobby allows a player to join an existing lobby
@@ -91,30 +116,13 @@ func (ctx *Context) HandleJoinLobby(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lobby.Lock()
-	if lobby.CurrentGame != nil {
-		lobby.Unlock()
-		http.Error(w, "Game in progress", http.StatusBadRequest)
-		return
-	}
-
 	// Check if browser already has a player_id cookie
 	var playerID string
 	var isRejoin bool
 	cookie, err := r.Cookie("player_id")
 	if err == nil && cookie.Value != "" {
-		existingPlayerID := cookie.Value
-		// Check if this player is already in the lobby
-		if _, exists := lobby.Players[existingPlayerID]; exists {
-			lobby.Unlock()
-			log.Printf("Player already in lobby: code=%s playerID=%s", roomCode, existingPlayerID)
-			// Already joined - just redirect to lobby
-			w.Header().Set("HX-Redirect", "/lobby/"+roomCode)
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		// Cookie exists but player not in lobby - rejoin with same ID
-		playerID = existingPlayerID
+		// Cookie exists - (re)join with the same ID
+		playerID = cookie.Value
 		isRejoin = true
 	} else {
 		// No cookie - create new player ID
@@ -122,41 +130,20 @@ func (ctx *Context) HandleJoinLobby(w http.ResponseWriter, r *http.Request) {
 		isRejoin = false
 	}
 
-	// Check if name is already taken by another player
-	if isNameTaken(lobby.Players, playerName, playerID) {
-		lobby.Unlock()
-		log.Printf("Name already taken: code=%s name=%s playerID=%s", roomCode, playerName, playerID)
+	if err := ctx.joinLobby(playerLogger(r, roomCode, playerID), lobby, playerID, playerName, isRejoin); err != nil {
+		if errorCode(err) != api.CodeNameTaken {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
 		// Use HTMX response headers to retarget the error message
 		w.Header().Set("Content-Type", "text/html")
 		w.Header().Set("HX-Retarget", "#join-error")
 		w.Header().Set("HX-Reswap", "outerHTML")
 		w.WriteHeader(http.StatusOK)
-		errorMsg := fmt.Sprintf("The name \"%s\" is already taken. Please choose a different name.", playerName)
-		w.Write([]byte(ctx.ErrorMessage(errorMsg)))
+		w.Write([]byte(ctx.ErrorMessage(err.Error())))
 		return
 	}
 
-	// Log the successful join/rejoin
-	if isRejoin {
-		log.Printf("Player rejoined lobby: code=%s playerID=%s name=%s", roomCode, playerID, playerName)
-	} else {
-		log.Printf("Player joined lobby: code=%s playerID=%s name=%s", roomCode, playerID, playerName)
-	}
-
-	// Add/re-add player to lobby
-	lobby.Players[playerID] = &models.Player{ID: playerID, Name: playerName}
-	if _, scoreExists := lobby.Scores[playerID]; !scoreExists {
-		lobby.Scores[playerID] = &models.PlayerScore{}
-	}
-	lobby.Unlock()
-
-	// Broadcast update to all clients
-	sse.Broadcast(lobby, sse.EventPlayerUpdate, ctx.PlayerList(lobby.Players))
-	sse.Broadcast(lobby, sse.EventScoreUpdate, ctx.ScoreTable(lobby))
-	sse.BroadcastPersonalized(lobby, func(pid string) string {
-		return ctx.HostControls(lobby, pid)
-	}, sse.EventControlsUpdate)
-
 	// Set cookie for player ID (session)
 	http.SetCookie(w, &http.Cookie{
 		Name:     "player_id",
@@ -172,6 +159,60 @@ func (ctx *Context) HandleJoinLobby(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// joinLobby adds a player to a waiting lobby; rejoin marks a returning player_id
+// Joining a lobby the player is already in succeeds without changes
+func (ctx *Context) joinLobby(logger *slog.Logger, lobby *models.Lobby, playerID, playerName string, rejoin bool) error {
+	if playerName == "" {
+		return reject(http.StatusBadRequest, api.CodeBadRequest, "Name is required")
+	}
+
+	err := ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		if lobby.CurrentGame != nil {
+			return reject(http.StatusConflict, api.CodeGameInProgress, "Game in progress")
+		}
+
+		// Check if this player is already in the lobby
+		if _, exists := lobby.Players[playerID]; exists {
+			return errAlreadyJoined
+		}
+
+		// Check if name is already taken by another player
+		if isNameTaken(lobby.Players, playerName, playerID) {
+			logger.Info("Name already taken", "name", logging.Name(playerName))
+			return reject(http.StatusConflict, api.CodeNameTaken, fmt.Sprintf("The name \"%s\" is already taken. Please choose a different name.", playerName))
+		}
+
+		// Add/re-add player to lobby
+		lobby.Players[playerID] = &models.Player{ID: playerID, Name: playerName}
+		if _, scoreExists := lobby.Scores[playerID]; !scoreExists {
+			lobby.Scores[playerID] = &models.PlayerScore{}
+		}
+		return nil
+	})
+	if errors.Is(err, errAlreadyJoined) {
+		logger.Debug("Player already in lobby")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Log the successful join/rejoin
+	if rejoin {
+		logger.Info("Player rejoined lobby", "name", logging.Name(playerName))
+	} else {
+		logger.Info("Player joined lobby", "name", logging.Name(playerName))
+	}
+
+	sse.Publish(lobby, events.PlayerJoined{PlayerID: playerID, Name: playerName, Rejoin: rejoin})
+	return nil
+}
+
+// errAlreadyJoined stops a join that would not change the lobby
+var errAlreadyJoined = errors.New("already in lobby")
+
 // HandleLobby displays the lobby page
 func (ctx *Context) HandleLobby(w http.ResponseWriter, r *http.Request) {
 	roomCode := strings.TrimPrefix(r.URL.Path, "/lobby/")
@@ -200,7 +241,7 @@ func (ctx *Context) HandleLobby(w http.ResponseWriter, r *http.Request) {
 		lobbyURL := fmt.Sprintf("%s/lobby/%s", ctx.BaseURL, roomCode)
 		png, err := qrcode.Encode(lobbyURL, qrcode.Medium, 256)
 		if err != nil {
-			log.Printf("Failed to generate QR code for lobby %s: %v", roomCode, err)
+			logging.From(r).Error("Failed to generate QR code", "lobby", roomCode, "err", err)
 		} else {
 			qrDataURL = template.URL(fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(png)))
 		}
@@ -213,6 +254,8 @@ func (ctx *Context) HandleLobby(w http.ResponseWriter, r *http.Request) {
 		IsHost        bool
 		Scores        map[string]*models.PlayerScore
 		QRCodeDataURL template.URL
+		HostControls  hostControlsView
+		HasHistory    bool
 	}{
 		RoomCode:      lobby.Code,
 		PlayerID:      playerID,
@@ -220,11 +263,72 @@ func (ctx *Context) HandleLobby(w http.ResponseWriter, r *http.Request) {
 		IsHost:        lobby.Host == playerID,
 		Scores:        lobby.Scores,
 		QRCodeDataURL: qrDataURL,
+		HostControls:  ctx.hostControlsView(lobby, playerID),
+		HasHistory:    len(lobby.History) > 0,
 	}
 
 	ctx.Templates.ExecuteTemplate(w, "lobby.html", data)
 }
 
+// HandleLobbySettings lets the host change the game mode before a game starts
+func (ctx *Context) HandleLobbySettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roomCode := strings.TrimPrefix(r.URL.Path, "/lobby-settings/")
+
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+
+	// Get player ID from cookie
+	cookie, err := r.Cookie("player_id")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	playerID := cookie.Value
+
+	r.ParseForm()
+	mode, ok := ctx.Modes.Lookup(models.GameMode(r.FormValue("mode")))
+	if !ok {
+		http.Error(w, "Unknown game mode", http.StatusBadRequest)
+		return
+	}
+
+	options := make(map[string]bool)
+	for _, opt := range mode.Options() {
+		options[opt.Key] = r.FormValue(opt.Key) != ""
+	}
+	err = ctx.updateLobby(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		if lobby.Host != playerID {
+			return reject(http.StatusForbidden, api.CodeForbidden, "Only host can change settings")
+		}
+		if lobby.CurrentGame != nil {
+			return reject(http.StatusBadRequest, api.CodeGameInProgress, "Game in progress")
+		}
+		lobby.Mode = mode.ID()
+		lobby.Options = maps.Clone(options)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	playerLogger(r, roomCode, playerID).Info("Lobby settings changed", "mode", mode.ID())
+
+	sse.Publish(lobby, events.SettingsChanged{Mode: mode.ID(), Options: options})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // HandleJoinLobbyScreen displays the join screen for entering name when scanning QR code
 func (ctx *Context) HandleJoinLobbyScreen(w http.ResponseWriter, r *http.Request) {
 	roomCode := strings.TrimPrefix(r.URL.Path, "/join/")
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+	"github.com/google/uuid"
+)
+
+// maxProfileName limits the length of a profile display name
+const maxProfileName = 32
+
+// profileOutcome is one player's part in a finished game, applied to their profile stats
+type profileOutcome struct {
+	PlayerID     string
+	Bot          bool // only counts towards the team strength of the rating
+	Impostor     bool
+	Won          bool
+	Votes        int
+	CorrectVotes int
+}
+
+// profileOutcomes collects the stats of every player in a finished game, including a forfeiting spy
+// Caller must hold lobby lock
+func profileOutcomes(lobby *models.Lobby, mode modes.Mode) []profileOutcome {
+	g := lobby.CurrentGame
+
+	outcomes := make(map[string]*profileOutcome)
+	for id, p := range lobby.Players {
+		impostor := mode.IsImpostor(g, id)
+		outcomes[id] = &profileOutcome{PlayerID: id, Bot: p.IsBot, Impostor: impostor, Won: impostor != g.Result.InnocentWon}
+	}
+	if g.SpyForfeited {
+		outcomes[g.SpyID] = &profileOutcome{PlayerID: g.SpyID, Impostor: true}
+	}
+
+	for _, round := range gameVoteRounds(g) {
+		for voter, suspect := range round {
+			if o, ok := outcomes[voter]; ok {
+				o.Votes++
+				if mode.IsImpostor(g, suspect) {
+					o.CorrectVotes++
+				}
+			}
+		}
+	}
+
+	list := make([]profileOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		list = append(list, *o)
+	}
+	return list
+}
+
+// recordProfileStats adds finished-game outcomes to the profiles of linked players
+// Profile ratings are rated against the other players' profile ratings (players without a profile count as new)
+// Runs without the lobby lock since profile stores may do network I/O
+// Games finishing at the same time on this instance are recorded one after the other, so a player in both is rated
+// from the rating the other game left rather than both starting from the same one.
+func (ctx *Context) recordProfileStats(innocentWon bool, outcomes []profileOutcome) {
+	ctx.profileMu.Lock()
+	defer ctx.profileMu.Unlock()
+
+	profiles := make(map[string]*models.Profile)
+	rated := make([]game.RatedPlayer, 0, len(outcomes))
+	for _, o := range outcomes {
+		rp := game.RatedPlayer{ID: o.PlayerID, Impostor: o.Impostor}
+		if !o.Bot {
+			if profile, ok := ctx.Profiles.ByPlayer(o.PlayerID); ok {
+				profiles[o.PlayerID] = profile
+				rp.Rating = profile.Stats.Rating
+			}
+		}
+		rated = append(rated, rp)
+	}
+	ratingDeltas := game.RateGame(rated, innocentWon)
+
+	now := time.Now()
+	for _, o := range outcomes {
+		profile, ok := profiles[o.PlayerID]
+		if !ok {
+			continue
+		}
+		err := ctx.Profiles.Update(profile.ID, func(p *models.Profile) {
+			s := &p.Stats
+			s.GamesPlayed++
+			if o.Impostor {
+				s.SpyGames++
+				if o.Won {
+					s.SpyWins++
+				}
+			} else {
+				s.InnocentGames++
+				if o.Won {
+					s.InnocentWins++
+				}
+			}
+			s.Votes += o.Votes
+			s.CorrectVotes += o.CorrectVotes
+			s.Rating.Apply(o.Impostor, ratingDeltas[o.PlayerID])
+			s.LastPlayedAt = now
+		})
+		if err != nil {
+			slog.Error("Failed to record profile stats", "profile", profile.ID, "err", err)
+		}
+	}
+}
+
+// newRecoveryCode returns a random recovery code like "ABCD-EFGH-JKLM" (60 bits of entropy)
+func newRecoveryCode() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	s := base32.StdEncoding.EncodeToString(b)[:12]
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12]
+}
+
+// hashRecoveryCode normalizes a recovery code (case, dashes, spaces) and hashes it
+// The codes are random, so a fast hash is enough to make a leaked store useless for recovery
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// playerProfile returns the profile linked to the request's player_id cookie
+func (ctx *Context) playerProfile(r *http.Request) (*models.Profile, bool) {
+	cookie, err := r.Cookie("player_id")
+	if err != nil || cookie.Value == "" {
+		return nil, false
+	}
+	return ctx.Profiles.ByPlayer(cookie.Value)
+}
+
+// profileError shows an error message above the profile forms
+func (ctx *Context) profileError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("HX-Retarget", "#profile-error")
+	w.Header().Set("HX-Reswap", "outerHTML")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`<div id="profile-error" role="alert">` + ctx.ErrorMessage(message) + `</div>`))
+}
+
+// profileStat is one row of the stats table
+type profileStat struct {
+	Label  string
+	Value  string
+	Detail string
+}
+
+// profileStats formats lifetime stats for display
+func profileStats(s models.ProfileStats) []profileStat {
+	rate := func(r float64, n, d int) (string, string) {
+		if d == 0 {
+			return "–", "no games yet"
+		}
+		return fmt.Sprintf("%.0f%%", r*100), fmt.Sprintf("%d of %d", n, d)
+	}
+	spyRate, spyDetail := rate(s.SpyWinRate(), s.SpyWins, s.SpyGames)
+	innocentRate, innocentDetail := rate(s.InnocentWinRate(), s.InnocentWins, s.InnocentGames)
+	voteRate, voteDetail := rate(s.CorrectVoteRate(), s.CorrectVotes, s.Votes)
+	if s.Votes == 0 {
+		voteDetail = "no votes yet"
+	}
+	return []profileStat{
+		{Label: "Games played", Value: fmt.Sprint(s.GamesPlayed)},
+		{Label: "Spy win rate", Value: spyRate, Detail: spyDetail},
+		{Label: "Innocent win rate", Value: innocentRate, Detail: innocentDetail},
+		{Label: "Correct votes", Value: voteRate, Detail: voteDetail},
+		{Label: "Spy rating", Value: s.Rating.Spy.String(), Detail: ratingDetail(s.Rating.Spy)},
+		{Label: "Innocent rating", Value: s.Rating.Innocent.String(), Detail: ratingDetail(s.Rating.Innocent)},
+	}
+}
+
+// ratingDetail describes how many games a rating is based on
+func ratingDetail(r models.RoleRating) string {
+	if r.Games == 0 {
+		return "no games yet"
+	}
+	if r.Games == 1 {
+		return "after 1 game"
+	}
+	return fmt.Sprintf("after %d games", r.Games)
+}
+
+// HandleProfileMux serves /profile (own profile or claim forms), /profile/{id} and the claim/recover actions
+func (ctx *Context) HandleProfileMux(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/profile"), "/")
+
+	if r.Method == http.MethodPost {
+		switch rest {
+		case "claim":
+			ctx.handleClaimProfile(w, r)
+		case "recover":
+			ctx.handleRecoverProfile(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var profile *models.Profile
+	own := false
+	if rest == "" {
+		profile, own = ctx.playerProfile(r)
+	} else {
+		var ok bool
+		profile, ok = ctx.Profiles.Get(rest)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if mine, ok := ctx.playerProfile(r); ok && mine.ID == profile.ID {
+			own = true
+		}
+	}
+
+	data := struct {
+		Profile *models.Profile
+		Own     bool
+		Stats   []profileStat
+	}{
+		Profile: profile,
+		Own:     own,
+	}
+	if profile != nil {
+		data.Stats = profileStats(profile.Stats)
+	}
+	ctx.Templates.ExecuteTemplate(w, "profile.html", data)
+}
+
+// handleClaimProfile creates a profile for the current browser and shows its recovery code once
+func (ctx *Context) handleClaimProfile(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		ctx.profileError(w, "Please choose a display name.")
+		return
+	}
+	if len([]rune(name)) > maxProfileName {
+		ctx.profileError(w, fmt.Sprintf("Display names can be at most %d characters.", maxProfileName))
+		return
+	}
+	if _, ok := ctx.playerProfile(r); ok {
+		ctx.profileError(w, "This browser already has a profile.")
+		return
+	}
+
+	playerID := ensurePlayerID(w, r)
+	code := newRecoveryCode()
+	profile := &models.Profile{
+		ID:           uuid.New().String(),
+		Name:         name,
+		RecoveryHash: hashRecoveryCode(code),
+		PlayerIDs:    []string{playerID},
+		CreatedAt:    time.Now(),
+	}
+	if err := ctx.Profiles.Create(profile); err != nil {
+		logging.From(r).Error("Failed to create profile", "player", playerID, "err", err)
+		ctx.profileError(w, "Could not create the profile, please try again.")
+		return
+	}
+	logging.From(r).Info("Profile claimed", "profile", profile.ID, "player", playerID)
+
+	ctx.Templates.ExecuteTemplate(w, "profile_claimed.html", struct {
+		Name         string
+		RecoveryCode string
+	}{
+		Name:         name,
+		RecoveryCode: code,
+	})
+}
+
+// handleRecoverProfile links the current browser to the profile matching a recovery code
+func (ctx *Context) handleRecoverProfile(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	code := r.FormValue("code")
+	if strings.TrimSpace(code) == "" {
+		ctx.profileError(w, "Please enter your recovery code.")
+		return
+	}
+
+	profile, ok := ctx.Profiles.ByRecoveryHash(hashRecoveryCode(code))
+	if !ok {
+		ctx.profileError(w, "That recovery code does not match any profile.")
+		return
+	}
+
+	playerID := ensurePlayerID(w, r)
+	if err := ctx.Profiles.Link(profile.ID, playerID); err != nil {
+		logging.From(r).Error("Failed to link profile", "profile", profile.ID, "player", playerID, "err", err)
+		ctx.profileError(w, "Could not restore the profile, please try again.")
+		return
+	}
+	logging.From(r).Info("Profile recovered", "profile", profile.ID, "player", playerID)
+
+	w.Header().Set("HX-Redirect", "/profile")
+	w.WriteHeader(http.StatusOK)
+}
@@ -51,59 +51,19 @@ func (ctx *Context) HandleResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate vote counts
-	voteCount := make(map[string]int)
-	for _, suspectID := range currentGame.Votes {
-		voteCount[suspectID]++
-	}
-
-	// Find most voted and check for tie
-	var mostVoted string
-	maxVotes := 0
-	isTie := false
-
-	// Handle spy forfeit case
-	if currentGame.SpyForfeited {
-		// Spy forfeited - innocents win by default
-		mostVoted = currentGame.SpyID
-		isTie = false
-	} else {
-		voteCounts := make(map[int]int) // count -> frequency
-		for _, count := range voteCount {
-			voteCounts[count]++
-			if count > maxVotes {
-				maxVotes = count
-			}
-		}
-		if voteCounts[maxVotes] > 1 {
-			isTie = true
-		} else {
-			for suspectID, count := range voteCount {
-				if count == maxVotes {
-					mostVoted = suspectID
-					break
-				}
-			}
-		}
-	}
-
-	innocentWon := !isTie && mostVoted == currentGame.SpyID
+	mode := ctx.Modes.Get(currentGame.Mode)
 
-	// Build challenges map
-	challengesMap := make(map[string]string)
-	for pid, info := range currentGame.PlayerInfo {
-		challengesMap[pid] = info.Challenge
-	}
-
-	// Build voted correctly map
-	votedCorrectly := make(map[string]bool)
-	for voterID, suspectID := range currentGame.Votes {
-		votedCorrectly[voterID] = suspectID == currentGame.SpyID
+	// The outcome is frozen when the game finishes
+	result := currentGame.Result
+	if result == nil {
+		playerLogger(r, roomCode, playerID).Error("Finished game has no result")
+		http.Redirect(w, r, "/lobby/"+roomCode, http.StatusSeeOther)
+		return
 	}
 
 	// Get spy info - handle case where spy left
 	var spy *models.Player
-	if currentGame.SpyForfeited {
+	if result.SpyForfeited {
 		// Create a temporary player object for the spy who left
 		spy = &models.Player{
 			ID:   currentGame.SpyID,
@@ -113,39 +73,52 @@ func (ctx *Context) HandleResults(w http.ResponseWriter, r *http.Request) {
 		spy = lobby.Players[currentGame.SpyID]
 	}
 
+	// Ballots of every round, including the ones that ended in a tie
+	nameOf := func(id string) string {
+		if p, ok := lobby.Players[id]; ok {
+			return p.Name
+		}
+		if id == currentGame.SpyID {
+			return currentGame.SpyName
+		}
+		return "(left)"
+	}
+	isImpostor := func(id string) bool { return mode.IsImpostor(currentGame, id) }
+	rounds := ballotRounds(gameVoteRounds(currentGame), nameOf, isImpostor)
+
 	data := struct {
 		RoomCode       string
 		PlayerID       string
 		IsHost         bool
 		Players        []*models.Player
 		Spy            *models.Player
-		Location       *models.Location
-		Challenges     map[string]string
 		Votes          map[string]string
 		VoteCount      map[string]int
 		VotedCorrectly map[string]bool
 		VoteRounds     int
+		Rounds         [][]voteBallot // one entry per voting round, tie-break rounds first
 		MostVoted      string
 		IsTie          bool
 		InnocentWon    bool
 		SpyForfeited   bool
+		Details        any // mode-specific results data
 	}{
 		RoomCode:       roomCode,
 		PlayerID:       playerID,
 		IsHost:         lobby.Host == playerID,
 		Players:        render.GetPlayerList(lobby.Players),
 		Spy:            spy,
-		Location:       currentGame.Location,
-		Challenges:     challengesMap,
 		Votes:          currentGame.Votes,
-		VoteCount:      voteCount,
-		VotedCorrectly: votedCorrectly,
+		VoteCount:      result.VoteCount,
+		VotedCorrectly: result.VotedCorrectly,
 		VoteRounds:     currentGame.VoteRound,
-		MostVoted:      mostVoted,
-		IsTie:          isTie,
-		InnocentWon:    innocentWon,
-		SpyForfeited:   currentGame.SpyForfeited,
+		Rounds:         rounds,
+		MostVoted:      result.MostVoted,
+		IsTie:          result.IsTie,
+		InnocentWon:    result.InnocentWon,
+		SpyForfeited:   result.SpyForfeited,
+		Details:        mode.ResultsView(currentGame, lobby),
 	}
 
-	ctx.Templates.ExecuteTemplate(w, "results.html", data)
+	ctx.Templates.ExecuteTemplate(w, mode.ResultsTemplate(), data)
 }
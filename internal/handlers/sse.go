@@ -2,15 +2,18 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aaronzipp/you-are-officially-sus/internal/game"
-	"github.com/aaronzipp/you-are-officially-sus/internal/models"
 	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
 )
 
+// serverRestartNotice is shown on open pages when the server shuts down
+const serverRestartNotice = "The server is restarting. This page will reconnect automatically in a moment."
+
 // formatSSEData formats multi-line data for SSE by prefixing each line with "data: "
 func formatSSEData(data string) string {
 	lines := strings.Split(data, "\n")
@@ -23,69 +26,108 @@ func formatSSEData(data string) string {
 	return formatted.String()
 }
 
-// HandleSSE handles Server-Sent Events for real-time updates
-func (ctx *Context) HandleSSE(w http.ResponseWriter, r *http.Request) {
-	if debug {
-		log.Printf("handleSSE called: %s", r.URL.Path)
+// writeSSEEvent writes one event, with an "id:" line when the event has an ID
+func writeSSEEvent(w http.ResponseWriter, id int64, event, data string) {
+	if id != 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\n%s\n", event, formatSSEData(data))
+}
+
+// sseConn is one SSE client connection of this instance
+// Every batch of writes is bounded by a write deadline, so a client that stopped reading
+// (e.g. a phone that lost its network) fails the write instead of blocking the stream forever.
+type sseConn struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	sub *sse.Subscription
+}
+
+// arm sets the write deadline for the next batch of writes
+func (c *sseConn) arm() {
+	// Not every ResponseWriter supports deadlines (e.g. in tests); those just write without one
+	c.rc.SetWriteDeadline(time.Now().Add(time.Duration(game.SSEWriteTimeoutSeconds) * time.Second))
+}
+
+// flush sends the buffered writes, records that the client was reached and clears the deadline
+// (an expired deadline on an idle HTTP/2 stream would otherwise reset it)
+func (c *sseConn) flush() error {
+	if err := c.rc.Flush(); err != nil {
+		c.sub.Failed(err)
+		return err
 	}
+	c.rc.SetWriteDeadline(time.Time{})
+	c.sub.Seen()
+	return nil
+}
+
+// heartbeat writes an SSE comment, which keeps proxies from closing an idle stream
+// and lets a dead connection surface as a failed write
+func (c *sseConn) heartbeat() error {
+	c.arm()
+	if _, err := fmt.Fprint(c.w, ": ping\n\n"); err != nil {
+		c.sub.Failed(err)
+		return err
+	}
+	return c.flush()
+}
 
+// writeSSERedirect answers an SSE request with a single nav-redirect event and ends the stream
+func (ctx *Context) writeSSERedirect(w http.ResponseWriter, roomCode, to string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: %s\n%s\n", sse.EventNavRedirect, formatSSEData(ctx.RedirectSnippet(roomCode, to)))
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// HandleSSE handles Server-Sent Events for real-time updates
+func (ctx *Context) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/sse/"), "/")
 	if len(parts) < 1 || len(parts) > 2 {
-		if debug {
-			log.Printf("handleSSE: invalid URL parts=%v", parts)
-		}
 		http.Error(w, "Invalid URL", http.StatusBadRequest)
 		return
 	}
 	roomCode := parts[0]
+	format := streamFormat(r)
 	var playerID string
 
 	if len(parts) == 2 {
 		// Legacy style: /sse/:room/:player
 		playerID = parts[1]
 	} else {
-		// Cookie-based: /sse/:room
-		lobby, pid, err := ctx.getLobbyAndPlayer(r, roomCode)
-		if err != nil {
-			// Not authorized or lobby validation failed: instruct client to navigate home via HTMX snippet
-			w.Header().Set("Content-Type", "text/event-stream")
-			w.Header().Set("Cache-Control", "no-cache")
-			w.Header().Set("Connection", "keep-alive")
-			fmt.Fprintf(w, "event: %s\n%s\n", sse.EventNavRedirect, formatSSEData(ctx.RedirectSnippet(roomCode, "/")))
-			if flusher, ok := w.(http.Flusher); ok {
-				flusher.Flush()
+		// Cookie-based: /sse/:room (API clients may send their bearer token instead)
+		pid, ok := "", false
+		if lobby, exists := ctx.LobbyStore.Get(roomCode); exists {
+			pid, ok = apiPlayer(r, lobby)
+		}
+		if !ok {
+			if format == sse.FormatJSON {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
 			}
+			// Not authorized or lobby validation failed: instruct client to navigate home via HTMX snippet
+			ctx.writeSSERedirect(w, roomCode, "/")
 			return
 		}
-		_ = lobby // validated but not needed yet
 		playerID = pid
 	}
 
-	if debug {
-		log.Printf("handleSSE: roomCode=%s playerID=%s", roomCode, playerID)
-	}
+	logger := playerLogger(r, roomCode, playerID)
 
-	lobby, exists := ctx.LobbyStore.Get(roomCode)
-	if !exists {
-		if debug {
-			log.Printf("handleSSE: room %s not found, sending nav-redirect to home", roomCode)
-		}
-		// Set SSE headers
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-
-		// Send nav-redirect snippet for HTMX to navigate home
-		fmt.Fprintf(w, "event: %s\n%s\n", sse.EventNavRedirect, formatSSEData(ctx.RedirectSnippet(roomCode, "/")))
-		if flusher, ok := w.(http.Flusher); ok {
-			flusher.Flush()
-		}
+	stream, redirect := ctx.openLiveStream(r, roomCode, playerID, format)
+	if redirect != "" && format == sse.FormatJSON {
+		// The lobby is gone
+		http.Error(w, "Lobby not found", http.StatusNotFound)
 		return
 	}
-
-	if debug {
-		log.Printf("handleSSE: found lobby, setting up SSE for player %s", playerID)
+	if redirect != "" {
+		ctx.writeSSERedirect(w, roomCode, redirect)
+		return
 	}
+	lobby := stream.lobby
 
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -98,101 +140,81 @@ func (ctx *Context) HandleSSE(w http.ResponseWriter, r *http.Request) {
 		flusher.Flush()
 	}
 
-	// Create client channel
-	clientChan := make(chan models.SSEMessage, game.SSEBufferSize)
-	sse.AddClient(lobby, clientChan, playerID)
-	defer sse.RemoveClient(lobby, clientChan)
-
-	lobby.RLock()
-	clientCount := lobby.SSEClientCount()
-	lobby.RUnlock()
-	if debug {
-		log.Printf("handleSSE: client %s connected, now have %d total clients", playerID, clientCount)
-	}
-
-	// Send initial data based on whether a game is in progress
-	lobby.RLock()
-	gameInProgress := lobby.CurrentGame != nil
-	if gameInProgress {
-		// Game in progress - send ready count or vote count with phase-specific event
-		g := lobby.CurrentGame
-		readyCount := 0
-		var countHTML string
-		var eventName string
-
-		// Count from appropriate ready state map based on game phase
-		switch g.Status {
-		case models.StatusReadyCheck:
-			for id := range lobby.Players {
-				if g.ReadyToReveal[id] {
-					readyCount++
-				}
-			}
-			totalPlayers := len(lobby.Players)
-			countHTML = ctx.ReadyCount(readyCount, totalPlayers, "players ready")
-			eventName = "ready-count-check"
-		case models.StatusRoleReveal:
-			for id := range lobby.Players {
-				if g.ReadyAfterReveal[id] {
-					readyCount++
-				}
-			}
-			totalPlayers := len(lobby.Players)
-			countHTML = ctx.ReadyCount(readyCount, totalPlayers, "players ready")
-			eventName = "ready-count-reveal"
-		case models.StatusPlaying:
-			for id := range lobby.Players {
-				if g.ReadyToVote[id] {
-					readyCount++
-				}
-			}
-			totalPlayers := len(lobby.Players)
-			countHTML = ctx.ReadyCount(readyCount, totalPlayers, "players ready to vote")
-			eventName = "ready-count-playing"
-		case models.StatusVoting:
-			// Send vote count for voting phase
-			voteCount := len(g.Votes)
-			totalPlayers := len(lobby.Players)
-			countHTML = ctx.VoteCount(voteCount, totalPlayers)
-			eventName = "vote-count-voting"
-		}
-		lobby.RUnlock()
-		if debug {
-			log.Printf("handleSSE: sending initial %s to player %s", eventName, playerID)
-		}
-		fmt.Fprintf(w, "event: %s\n%s\n", eventName, formatSSEData(countHTML))
-	} else {
-		// No game - send lobby data
-		playerListHTML := ctx.PlayerList(lobby.Players)
-		hostControlsHTML := ctx.HostControls(lobby, playerID)
-		scoreTableHTML := ctx.ScoreTable(lobby)
-		lobby.RUnlock()
-		if debug {
-			log.Printf("handleSSE: sending initial lobby data to player %s", playerID)
+	sub := sse.Subscribe(lobby, playerID, sse.TransportSSE, format)
+	defer sub.Close()
+	conn := &sseConn{w: w, rc: http.NewResponseController(w), sub: sub}
+	conn.arm()
+
+	logger.Debug("handleSSE: client connected", "format", format, "clients", sse.ClientCount(roomCode))
+
+	// A reconnecting EventSource sends the ID of the last event it saw: replay what it missed
+	// before the current state below. Messages already replayed are skipped in the loop.
+	var lastSent int64
+	if lastID, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil && lastID > 0 {
+		missed, ok := stream.missedEvents(lastID)
+		if !ok && format == sse.FormatHTML {
+			// The missed events are gone, so reload the page of the current phase instead
+			fmt.Fprintf(w, "event: %s\n%s\n", sse.EventNavRedirect, formatSSEData(ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, stream.current))))
+			conn.flush()
+			return
 		}
-		fmt.Fprintf(w, "event: %s\n%s\n", sse.EventPlayerUpdate, formatSSEData(playerListHTML))
-		fmt.Fprintf(w, "event: %s\n%s\n", sse.EventControlsUpdate, formatSSEData(hostControlsHTML))
-		if scoreTableHTML != "" {
-			fmt.Fprintf(w, "event: %s\n%s\n", sse.EventScoreUpdate, formatSSEData(scoreTableHTML))
+		for _, msg := range missed {
+			writeSSEEvent(w, msg.ID, msg.Event, msg.Data)
+			lastSent = msg.ID
 		}
 	}
-	w.(http.Flusher).Flush()
+
+	for _, msg := range ctx.initialEvents(stream) {
+		writeSSEEvent(w, msg.ID, msg.Event, msg.Data)
+	}
+	if err := conn.flush(); err != nil {
+		logger.Info("handleSSE: initial write failed", "err", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(time.Duration(game.SSEHeartbeatSeconds) * time.Second)
+	defer heartbeat.Stop()
 
 	// Listen for updates
 	reqCtx := r.Context()
 	for {
 		select {
 		case <-reqCtx.Done():
-			log.Printf("handleSSE: SSE connection closed for player %s in room %s (normal navigation or disconnect)", playerID, roomCode)
-			// Don't call handlePlayerDisconnect here - SSE connections close during normal page navigation
+			logger.Debug("handleSSE: connection closed (normal navigation or disconnect)")
+			// Don't remove the player here - SSE connections close during normal page navigation
 			// Players are only removed when they explicitly leave via HandleLeaveLobby or HandleLeaveLobbyWithHost
 			return
-		case msg := <-clientChan:
-			if debug {
-				log.Printf("handleSSE: sending event=%s to player %s", msg.Event, playerID)
+		case <-sse.Closing():
+			// Server is shutting down: tell the page and end the stream so the drain can finish
+			// (the EventSource reconnects on its own once the server is back)
+			if format == sse.FormatHTML {
+				conn.arm()
+				fmt.Fprintf(w, "event: %s\n%s\n", sse.EventServerNotice, formatSSEData(ctx.ServerNotice(serverRestartNotice)))
+				conn.flush()
+			}
+			return
+		case <-heartbeat.C:
+			if err := conn.heartbeat(); err != nil {
+				logger.Info("handleSSE: heartbeat failed, dropping connection", "err", err)
+				return
+			}
+		case msg, ok := <-sub.C:
+			if !ok {
+				// The client fell too far behind; the EventSource reconnects and replays what it missed
+				logger.Warn("handleSSE: client fell behind, closing stream")
+				return
+			}
+			if msg.ID != 0 && msg.ID <= lastSent {
+				continue // already replayed
+			}
+			logger.Debug("handleSSE: sending event", "event", msg.Event, "id", msg.ID)
+			conn.arm()
+			writeSSEEvent(w, msg.ID, msg.Event, msg.Data)
+			if err := conn.flush(); err != nil {
+				logger.Info("handleSSE: sending event failed, dropping connection", "event", msg.Event, "err", err)
+				return
 			}
-			fmt.Fprintf(w, "event: %s\n%s\n", msg.Event, formatSSEData(msg.Data))
-			w.(http.Flusher).Flush()
+			heartbeat.Reset(time.Duration(game.SSEHeartbeatSeconds) * time.Second)
 		}
 	}
 }
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+)
+
+// liveStream is a validated live connection request, shared by the SSE and WebSocket transports
+type liveStream struct {
+	lobby    *models.Lobby
+	roomCode string
+	playerID string
+	format   string            // sse.FormatHTML or sse.FormatJSON
+	current  models.GameStatus // lobby phase when the stream opened
+	logger   *slog.Logger
+}
+
+// streamFormat returns the message format a live connection asked for with ?format= (HTML by default)
+func streamFormat(r *http.Request) string {
+	if r.URL.Query().Get("format") == sse.FormatJSON {
+		return sse.FormatJSON
+	}
+	return sse.FormatHTML
+}
+
+// openLiveStream checks that the lobby exists and that the client's page still matches its phase
+// A non-empty redirect is the page the client should load instead of streaming
+func (ctx *Context) openLiveStream(r *http.Request, roomCode, playerID, format string) (stream *liveStream, redirect string) {
+	logger := playerLogger(r, roomCode, playerID)
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		logger.Debug("liveStream: lobby not found, redirecting home")
+		return nil, "/"
+	}
+
+	lobby.RLock()
+	current := models.StatusWaiting
+	if lobby.CurrentGame != nil {
+		current = lobby.CurrentGame.Status
+	}
+	lobby.RUnlock()
+
+	// Resync clients whose page no longer matches the lobby phase
+	// (e.g. the connection came back after a server restart or a missed nav-redirect)
+	// JSON clients have no page; their snapshot carries the phase
+	if phase := r.URL.Query().Get("phase"); format == sse.FormatHTML && phase != "" && models.GameStatus(phase) != current {
+		logger.Debug("liveStream: page is on another phase, resyncing", "page_phase", phase, "phase", current)
+		return nil, game.PhasePathFor(roomCode, current)
+	}
+	return &liveStream{lobby: lobby, roomCode: roomCode, playerID: playerID, format: format, current: current, logger: logger}, ""
+}
+
+// missedEvents returns the events a reconnecting client missed after lastID
+// ok is false when they are gone; a page should then reload the page of the current phase,
+// a JSON client starts over from the snapshot that follows
+func (s *liveStream) missedEvents(lastID int64) ([]models.SSEMessage, bool) {
+	missed, ok := sse.Replay(s.roomCode, s.playerID, s.format, lastID)
+	if !ok {
+		s.logger.Debug("liveStream: cannot replay missed events, reloading", "last_event_id", lastID)
+		return nil, false
+	}
+	s.logger.Debug("liveStream: replaying missed events", "count", len(missed), "last_event_id", lastID)
+	events := make([]models.SSEMessage, len(missed))
+	for i, msg := range missed {
+		events[i] = models.SSEMessage{ID: msg.ID, Event: msg.Event, Data: msg.Data}
+	}
+	return events, true
+}
+
+// initialEvents renders the current lobby state every new live connection starts with
+func (ctx *Context) initialEvents(s *liveStream) []models.SSEMessage {
+	s.lobby.RLock()
+	snapshot := events.NewSnapshot(s.lobby)
+	s.lobby.RUnlock()
+	snapshot.Notice = ctx.Notice()
+
+	initial := sse.Render(s.lobby, s.format, s.playerID, snapshot)
+	if s.format == sse.FormatHTML {
+		// Show the maintenance message, or clear a restart notice left over from before a reconnect
+		initial = append(initial, models.SSEMessage{Event: sse.EventServerNotice, Data: ctx.ServerNotice(snapshot.Notice)})
+	}
+	return initial
+}
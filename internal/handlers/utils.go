@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/store"
+	"github.com/google/uuid"
 )
 
 // getLobbyAndPlayer validates membership using session cookie
@@ -28,6 +33,41 @@ func (ctx *Context) getLobbyAndPlayer(r *http.Request, roomCode string) (*models
 	return lobby, playerID, nil
 }
 
+// ensurePlayerID returns the request's player_id, issuing a new cookie when there is none
+func ensurePlayerID(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie("player_id"); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	playerID := uuid.New().String()
+	http.SetCookie(w, &http.Cookie{
+		Name:     "player_id",
+		Value:    playerID,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		// Secure: true, // enable when serving over HTTPS
+	})
+	return playerID
+}
+
+// updateLobby applies change to a lobby and writes it back to the store so other instances see it
+// change takes the lobby lock itself and may run more than once (see store.LobbyStore.Update)
+func (ctx *Context) updateLobby(lobby *models.Lobby, change func() error) error {
+	var changeErr error
+	err := ctx.LobbyStore.Update(lobby, func() error {
+		changeErr = change()
+		return changeErr
+	})
+	switch {
+	case err == nil || err == changeErr:
+		return err
+	case errors.Is(err, store.ErrLobbyNotFound):
+		return reject(http.StatusNotFound, api.CodeNotFound, "Lobby not found")
+	}
+	slog.Error("Saving lobby failed", "lobby", lobby.Code, "err", err)
+	return reject(http.StatusServiceUnavailable, api.CodeUnavailable, "The lobby could not be saved. Please try again.")
+}
+
 // isNameTaken checks if a name is already taken in the lobby (case-insensitive)
 // excludePlayerID allows a player to keep their own name (for rejoin scenarios)
 func isNameTaken(players map[string]*models.Player, name string, excludePlayerID string) bool {
@@ -42,3 +82,37 @@ func isNameTaken(players map[string]*models.Player, name string, excludePlayerID
 	}
 	return false
 }
+
+// actionError is a rejected lobby action, shared by the page handlers and the JSON API
+type actionError struct {
+	status  int    // HTTP status
+	code    string // api.Code* value
+	message string
+}
+
+func (e *actionError) Error() string {
+	return e.message
+}
+
+// reject creates an actionError
+func reject(status int, code, message string) error {
+	return &actionError{status: status, code: code, message: message}
+}
+
+// errorStatus returns the HTTP status of an error returned by a lobby action
+func errorStatus(err error) int {
+	var ae *actionError
+	if errors.As(err, &ae) {
+		return ae.status
+	}
+	return http.StatusBadRequest
+}
+
+// errorCode returns the API error code of an error returned by a lobby action
+func errorCode(err error) string {
+	var ae *actionError
+	if errors.As(err, &ae) {
+		return ae.code
+	}
+	return api.CodeBadRequest
+}
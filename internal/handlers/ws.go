@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+	"github.com/gorilla/websocket"
+)
+
+// Events only sent over WebSocket, in reply to a client action
+const (
+	wsEventActionOK    = "action-ok"
+	wsEventActionError = "action-error"
+)
+
+// wsMaxMessageSize limits the size of a client action
+const wsMaxMessageSize = 4096
+
+// wsUpgrader upgrades /ws requests; the default origin check only accepts pages served from this host
+var wsUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// wsAction is a message from a WebSocket client
+type wsAction struct {
+	Action  string `json:"action"`            // "ready" or "vote"
+	Suspect string `json:"suspect,omitempty"` // player ID voted for
+}
+
+// wsEvent is a message to a WebSocket client: a live event (the same ones SSE delivers) or an action reply
+type wsEvent struct {
+	ID     int64  `json:"id,omitempty"` // live events only, send it back as last_event_id when reconnecting
+	Event  string `json:"event"`
+	Data   any    `json:"data"`             // HTML fragment, or an events.Envelope with format=json
+	Action string `json:"action,omitempty"` // the action an action-ok/action-error answers
+}
+
+// wsLiveEvent wraps a live event; JSON events are embedded as objects rather than strings
+func wsLiveEvent(format string, msg models.SSEMessage) wsEvent {
+	ev := wsEvent{ID: msg.ID, Event: msg.Event, Data: msg.Data}
+	if format == sse.FormatJSON {
+		ev.Data = json.RawMessage(msg.Data)
+	}
+	return ev
+}
+
+// wsConn writes to one WebSocket client; only the handler goroutine writes
+type wsConn struct {
+	conn *websocket.Conn
+	sub  *sse.Subscription
+}
+
+// deadline is the write deadline for the next message
+func (c *wsConn) deadline() time.Time {
+	return time.Now().Add(time.Duration(game.SSEWriteTimeoutSeconds) * time.Second)
+}
+
+// send writes one message and records that the client was reached
+func (c *wsConn) send(ev wsEvent) error {
+	c.conn.SetWriteDeadline(c.deadline())
+	if err := c.conn.WriteJSON(ev); err != nil {
+		if c.sub != nil {
+			c.sub.Failed(err)
+		}
+		return err
+	}
+	if c.sub != nil {
+		c.sub.Seen()
+	}
+	return nil
+}
+
+// close ends the connection with a close frame
+func (c *wsConn) close(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), c.deadline())
+	c.conn.Close()
+}
+
+// HandleWebSocket delivers a lobby's live events over WebSocket and accepts ready and vote actions
+// It is an alternative to /sse/{code} for clients behind proxies that buffer event streams
+func (ctx *Context) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	roomCode := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ws/"), "/")
+	if roomCode == "" || strings.Contains(roomCode, "/") {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+	// Pages send the player_id cookie; API clients may send their bearer token instead
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	playerID, ok := apiPlayer(r, lobby)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Browser pages get HTML fragments; other clients can ask for typed JSON events
+	format := streamFormat(r)
+	stream, redirect := ctx.openLiveStream(r, roomCode, playerID, format)
+	logger := playerLogger(r, roomCode, playerID)
+
+	ws, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already answered with an HTTP error
+		logger.Info("handleWebSocket: upgrade failed", "err", err)
+		return
+	}
+	ctx.running.Add(1)
+	defer ctx.running.Done()
+	ws.SetReadLimit(wsMaxMessageSize)
+	conn := &wsConn{conn: ws}
+
+	if redirect != "" {
+		conn.send(wsEvent{Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(roomCode, redirect)})
+		conn.close(websocket.CloseNormalClosure, "")
+		return
+	}
+
+	sub := sse.Subscribe(stream.lobby, playerID, sse.TransportWebSocket, format)
+	defer sub.Close()
+	conn.sub = sub
+	logger.Info("handleWebSocket: client connected", "format", format)
+
+	// Browsers cannot set headers on a WebSocket, so the last seen event ID comes in the query
+	var lastSent int64
+	if lastID, err := strconv.ParseInt(r.URL.Query().Get("last_event_id"), 10, 64); err == nil && lastID > 0 {
+		missed, ok := stream.missedEvents(lastID)
+		if !ok && format == sse.FormatHTML {
+			conn.send(wsEvent{Event: sse.EventNavRedirect, Data: ctx.RedirectSnippet(roomCode, game.PhasePathFor(roomCode, stream.current))})
+			conn.close(websocket.CloseNormalClosure, "")
+			return
+		}
+		for _, msg := range missed {
+			if err := conn.send(wsLiveEvent(format, msg)); err != nil {
+				ws.Close()
+				return
+			}
+			lastSent = msg.ID
+		}
+	}
+	for _, msg := range ctx.initialEvents(stream) {
+		if err := conn.send(wsLiveEvent(format, msg)); err != nil {
+			logger.Info("handleWebSocket: initial write failed", "err", err)
+			ws.Close()
+			return
+		}
+	}
+
+	// A client that answers neither pings nor sends anything within pongWait is gone
+	heartbeatInterval := time.Duration(game.SSEHeartbeatSeconds) * time.Second
+	pongWait := 2*heartbeatInterval + time.Duration(game.SSEWriteTimeoutSeconds)*time.Second
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		sub.Seen()
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	// Actions are read and applied on their own goroutine; replies go back through the writer below
+	replies := make(chan wsEvent, 1)
+	readDone := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	ctx.running.Go(func() {
+		for {
+			var action wsAction
+			if err := ws.ReadJSON(&action); err != nil {
+				readDone <- err
+				return
+			}
+			ws.SetReadDeadline(time.Now().Add(pongWait))
+			reply := ctx.applyWebSocketAction(logger, roomCode, playerID, action)
+			select {
+			case replies <- reply:
+			case <-done:
+				return
+			}
+		}
+	})
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case err := <-readDone:
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				logger.Info("handleWebSocket: client closed the connection", "code", closeErr.Code)
+			} else {
+				logger.Info("handleWebSocket: connection lost", "err", err)
+			}
+			ws.Close()
+			return
+		case <-sse.Closing():
+			// Server is shutting down: tell the client, which should reconnect with its last event ID
+			if format == sse.FormatHTML {
+				conn.send(wsEvent{Event: sse.EventServerNotice, Data: ctx.ServerNotice(serverRestartNotice)})
+			}
+			conn.close(websocket.CloseGoingAway, "server restarting")
+			return
+		case <-heartbeat.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, conn.deadline()); err != nil {
+				sub.Failed(err)
+				logger.Info("handleWebSocket: ping failed, dropping connection", "err", err)
+				ws.Close()
+				return
+			}
+		case reply := <-replies:
+			if err := conn.send(reply); err != nil {
+				ws.Close()
+				return
+			}
+		case msg, ok := <-sub.C:
+			if !ok {
+				logger.Warn("handleWebSocket: client fell behind, closing connection")
+				conn.close(websocket.CloseTryAgainLater, "fell behind, reconnect with last_event_id")
+				return
+			}
+			if msg.ID != 0 && msg.ID <= lastSent {
+				continue // already replayed
+			}
+			if err := conn.send(wsLiveEvent(format, msg)); err != nil {
+				logger.Info("handleWebSocket: sending event failed, dropping connection", "event", msg.Event, "err", err)
+				ws.Close()
+				return
+			}
+		}
+	}
+}
+
+// applyWebSocketAction applies a ready or vote action like the matching POST /game/{code}/... endpoint
+// The reply carries the same HTML fragment the POST would have returned
+func (ctx *Context) applyWebSocketAction(logger *slog.Logger, roomCode, playerID string, action wsAction) wsEvent {
+	fail := func(message string) wsEvent {
+		return wsEvent{Event: wsEventActionError, Action: action.Action, Data: message}
+	}
+	lobby, exists := ctx.LobbyStore.Get(roomCode)
+	if !exists {
+		return fail("Lobby not found")
+	}
+
+	switch action.Action {
+	case "ready":
+		update, err := ctx.applyReady(logger, lobby, roomCode, playerID, true)
+		if err != nil {
+			return fail(err.Error())
+		}
+		ctx.broadcastReady(lobby, roomCode, playerID, update)
+		return wsEvent{Event: wsEventActionOK, Action: action.Action, Data: readyButtonHTML(update.status, update.isReady)}
+	case "vote":
+		if action.Suspect == "" {
+			return fail("Missing suspect")
+		}
+		update, err := ctx.applyVote(lobby, roomCode, playerID, action.Suspect)
+		if err != nil {
+			return fail(err.Error())
+		}
+		ctx.broadcastVote(lobby, roomCode, update)
+		return wsEvent{Event: wsEventActionOK, Action: action.Action, Data: ctx.VotedConfirmation()}
+	}
+	return fail("Unknown action")
+}
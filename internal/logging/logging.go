@@ -0,0 +1,143 @@
+// Package logging configures the server's structured logger (log/slog)
+// Lines logged while serving a request carry its request ID; handlers add the lobby code and player ID.
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Options configure New
+type Options struct {
+	Level       slog.Level
+	Format      string // "text" (default) or "json"
+	RedactNames bool   // replace player names with a pseudonym that is stable until the next restart
+}
+
+// ParseLevel parses a LOG_LEVEL value: debug, info (default), warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// New creates a logger writing to w
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: names(opts.RedactNames)}
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", opts.Format)
+	}
+}
+
+// playerName and playerNames mark values that may be redacted
+type (
+	playerName  string
+	playerNames []string
+)
+
+// Name marks a player name, which is redacted when RedactNames is set
+func Name(name string) slog.Value {
+	return slog.AnyValue(playerName(name))
+}
+
+// Names marks a list of player names, see Name
+func Names(names []string) slog.Value {
+	return slog.AnyValue(playerNames(names))
+}
+
+// names renders the values of Name and Names, pseudonymized with a per-process key when redact is set
+// The key is never stored, so a pseudonym can only be linked to a name while the process runs.
+func names(redact bool) func(groups []string, a slog.Attr) slog.Attr {
+	key := make([]byte, 32)
+	rand.Read(key)
+	render := func(name string) string {
+		if !redact {
+			return name
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(name))
+		return "anon-" + hex.EncodeToString(mac.Sum(nil)[:4])
+	}
+
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindAny {
+			return a
+		}
+		switch v := a.Value.Any().(type) {
+		case playerName:
+			a.Value = slog.StringValue(render(string(v)))
+		case playerNames:
+			rendered := make([]string, len(v))
+			for i, name := range v {
+				rendered[i] = render(name)
+			}
+			a.Value = slog.StringValue(strings.Join(rendered, ", "))
+		}
+		return a
+	}
+}
+
+type loggerKey struct{}
+
+// Middleware gives every request an ID and a logger carrying it
+// A valid X-Request-ID header from a proxy is kept; the ID is echoed in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		logger := slog.Default().With("request_id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)))
+	})
+}
+
+// From returns the logger of a request, or the default logger outside of Middleware
+func From(r *http.Request) *slog.Logger {
+	if logger, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// newRequestID returns 8 random bytes in hex
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts short IDs of letters, digits, dots, dashes and underscores
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
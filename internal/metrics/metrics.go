@@ -0,0 +1,159 @@
+// Package metrics exposes the server's Prometheus metrics, served at /metrics
+// Lobby and game gauges are read from the lobby store on every scrape; the rest is counted as it happens.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
+	"github.com/aaronzipp/you-are-officially-sus/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sus"
+
+// Outcomes of GameFinished
+const (
+	OutcomeInnocentWin = "innocent_win"
+	OutcomeSpyWin      = "spy_win" // the hidden team won the vote
+	OutcomeForfeit     = "forfeit" // the spy left, so the innocents won
+	OutcomeAbort       = "abort"   // the game ended without a result
+)
+
+// gameStatuses are the phases reported by sus_games, in game order
+var gameStatuses = []models.GameStatus{
+	models.StatusReadyCheck,
+	models.StatusRoleReveal,
+	models.StatusPlaying,
+	models.StatusVoting,
+	models.StatusFinished,
+}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	gamesFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_finished_total",
+		Help:      "Games that finished, by outcome (innocent_win, spy_win, forfeit, abort).",
+	}, []string{"outcome"})
+
+	lobbiesExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lobbies_expired_total",
+		Help:      "Idle lobbies removed by the janitor of this instance.",
+	})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code (live event streams excluded).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		gamesFinished,
+		lobbiesExpired,
+		requestDuration,
+		liveCollector{},
+	)
+	// Report every outcome from the start, so rate() works before the first game of a kind ends
+	for _, outcome := range []string{OutcomeInnocentWin, OutcomeSpyWin, OutcomeForfeit, OutcomeAbort} {
+		gamesFinished.WithLabelValues(outcome)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterLobbies reports the lobbies and games of a store on every scrape
+func RegisterLobbies(s store.LobbyStore) {
+	registry.MustRegister(lobbyCollector{store: s})
+}
+
+// GameFinished counts a game that ended with one of the Outcome* constants
+func GameFinished(outcome string) {
+	gamesFinished.WithLabelValues(outcome).Inc()
+}
+
+// LobbyExpired counts an idle lobby removed by the janitor
+func LobbyExpired() {
+	lobbiesExpired.Inc()
+}
+
+// Instrument records the latency of every request handled by h under the route label
+func Instrument(route string, h http.HandlerFunc) http.Handler {
+	return promhttp.InstrumentHandlerDuration(requestDuration.MustCurryWith(prometheus.Labels{"route": route}), h)
+}
+
+var (
+	lobbiesDesc = prometheus.NewDesc(namespace+"_lobbies", "Lobbies in the lobby store.", nil, nil)
+	gamesDesc   = prometheus.NewDesc(namespace+"_games", "Games in progress by phase.", []string{"status"}, nil)
+)
+
+// lobbyCollector counts the lobbies of the store and their games by phase
+// With a shared store every instance reports the same totals.
+type lobbyCollector struct {
+	store store.LobbyStore
+}
+
+func (c lobbyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lobbiesDesc
+	ch <- gamesDesc
+}
+
+func (c lobbyCollector) Collect(ch chan<- prometheus.Metric) {
+	lobbies := c.store.All()
+	games := make(map[models.GameStatus]int, len(gameStatuses))
+	for _, lobby := range lobbies {
+		lobby.RLock()
+		if g := lobby.CurrentGame; g != nil {
+			games[g.Status]++
+		}
+		lobby.RUnlock()
+	}
+
+	ch <- prometheus.MustNewConstMetric(lobbiesDesc, prometheus.GaugeValue, float64(len(lobbies)))
+	for _, status := range gameStatuses {
+		ch <- prometheus.MustNewConstMetric(gamesDesc, prometheus.GaugeValue, float64(games[status]), string(status))
+	}
+}
+
+var (
+	clientsDesc = prometheus.NewDesc(namespace+"_sse_clients", "Live clients connected to this instance by transport.", []string{"transport"}, nil)
+	timeoutDesc = prometheus.NewDesc(namespace+"_sse_send_timeouts_total", "Writes to live clients that ran into the write deadline.", nil, nil)
+	droppedDesc = prometheus.NewDesc(namespace+"_sse_messages_dropped_total", "Messages discarded from the full queue of a slow client.", nil, nil)
+	mergedDesc  = prometheus.NewDesc(namespace+"_sse_messages_coalesced_total", "Messages replaced by a newer message of the same event in a slow client's queue.", nil, nil)
+	kickedDesc  = prometheus.NewDesc(namespace+"_sse_overflow_disconnects_total", "Clients disconnected because their queue was full.", nil, nil)
+)
+
+// liveCollector reports the live connections and delivery counters of internal/sse
+type liveCollector struct{}
+
+func (liveCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clientsDesc
+	ch <- timeoutDesc
+	ch <- droppedDesc
+	ch <- mergedDesc
+	ch <- kickedDesc
+}
+
+func (liveCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, transport := range []string{sse.TransportSSE, sse.TransportWebSocket} {
+		ch <- prometheus.MustNewConstMetric(clientsDesc, prometheus.GaugeValue, float64(sse.Connections(transport)), transport)
+	}
+	stats := sse.Stats()
+	ch <- prometheus.MustNewConstMetric(timeoutDesc, prometheus.CounterValue, float64(stats.SendTimeouts))
+	ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(mergedDesc, prometheus.CounterValue, float64(stats.Coalesced))
+	ch <- prometheus.MustNewConstMetric(kickedDesc, prometheus.CounterValue, float64(stats.Disconnected))
+}
@@ -4,18 +4,36 @@ import "time"
 
 // Game represents an active game session (ephemeral)
 type Game struct {
-	Location        *Location
-	SpyID           string
-	SpyName         string                     // Store spy name in case they leave
+	Mode    GameMode
+	SpyID   string // Spy (Spyfall) or undercover player (Undercover)
+	SpyName string // Store spy name in case they leave
+
+	// Mode-specific state, filled in by the game mode's Setup
+	Secrets     map[string]string // e.g. the Spyfall location; keys belong to the mode
+	MrWhiteID   string            // Undercover: player who receives no word (optional)
+	MrWhiteName string
+
 	FirstQuestioner string                     // Player ID of who asks the first question
 	PlayerInfo      map[string]*GamePlayerInfo // game-specific player data
 	Status          GameStatus
-	PlayStartedAt   time.Time // When the Playing phase started (for timer sync)
+	PlayStartedAt   time.Time                // When the Playing phase started (for timer sync)
+	PhaseStartedAt  map[GameStatus]time.Time // When each phase was entered (set by SetStatus)
 
 	ReadyToReveal    map[string]bool // Phase 1: Ready to see role (all players required)
 	ReadyAfterReveal map[string]bool // Phase 2: Confirmed saw role (all players required)
 	ReadyToVote      map[string]bool // Phase 3: Ready to vote (>50% required)
 	Votes            map[string]string
-	VoteRound        int  // Track voting rounds for tie-breaking
-	SpyForfeited     bool // True if spy left the game
+	VoteHistory      []map[string]string // Ballots of earlier rounds that ended in a tie
+	VoteRound        int                 // Track voting rounds for tie-breaking
+	SpyForfeited     bool                // True if spy left the game
+	Result           *GameResult         // Set once when the game finishes
+}
+
+// SetStatus moves the game to a new phase and records when it started
+func (g *Game) SetStatus(status GameStatus) {
+	g.Status = status
+	if g.PhaseStartedAt == nil {
+		g.PhaseStartedAt = make(map[GameStatus]time.Time)
+	}
+	g.PhaseStartedAt[status] = time.Now()
 }
@@ -0,0 +1,4 @@
+package models
+
+// GameMode identifies which ruleset a lobby plays (see internal/modes)
+type GameMode string
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// GameRecord is the immutable archive of a finished game, kept in Lobby.History
+type GameRecord struct {
+	Number int // 1-based game number within the lobby
+	Mode   GameMode
+
+	Secrets        []Secret                 // the mode's secrets, most important first
+	Players        []RecordPlayer           // everyone who was scored, plus a forfeiting spy
+	VoteRounds     []map[string]string      // voterID -> suspectID, one map per round (tie-break rounds first)
+	Result         GameResult               // frozen outcome of the final round
+	PhaseStartedAt map[GameStatus]time.Time // when each phase was entered
+	FinishedAt     time.Time
+}
+
+// Secret is a labelled secret of a game, e.g. the Spyfall location
+type Secret struct {
+	Key   string // stable name within the mode, e.g. "location"
+	Label string // e.g. "Location"
+	Value string
+}
+
+// RecordPlayer is a player's role and outcome in an archived game
+type RecordPlayer struct {
+	ID         string
+	Name       string
+	Role       string // mode-specific role name, e.g. "Spy" or "Civilian"
+	Impostor   bool
+	Challenge  string      // Spyfall
+	Word       string      // Undercover (empty for Mr. White)
+	Left       bool        // left before the end (forfeiting spy)
+	ScoreDelta PlayerScore // change applied to the lobby score
+}
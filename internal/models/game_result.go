@@ -0,0 +1,15 @@
+package models
+
+// GameResult is the outcome of a finished game
+// It is computed once when the game enters StatusFinished; the results page, scores
+// and history all read from it so they can never disagree
+type GameResult struct {
+	VoteCount      map[string]int         // suspectID -> votes in the final round
+	MostVoted      string                 // player voted out (empty on a tie or forfeit)
+	IsTie          bool                   // final round ended without a single leader
+	InnocentWon    bool                   // decides the scoring
+	SpyForfeited   bool                   // the spy left before the vote
+	VotedCorrectly map[string]bool        // voterID -> voted for an impostor in the final round
+	ScoreDeltas    map[string]PlayerScore // playerID -> change applied to the lobby score
+	RatingDeltas   map[string]float64     // playerID -> change of the lobby rating for the side they played
+}
@@ -1,20 +1,49 @@
 package models
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Lobby represents a persistent game lobby
 type Lobby struct {
+	LobbyState
+	mu sync.RWMutex
+
+	lastActivity atomic.Int64 // unix nanoseconds of the last request or SSE (dis)connect
+	afterSave    []func()     // side effects of the change being stored, see AfterSave
+
+	clientsMu  sync.Mutex
+	sseClients map[chan SSEMessage]*SSEClient // live connections to this instance
+}
+
+// SSEClient is one live connection (SSE or WebSocket) to a lobby held by this instance
+type SSEClient struct {
+	PlayerID    string
+	Transport   string // "sse" or "websocket"
+	ConnectedAt time.Time
+	LastSeen    time.Time // last time an event or heartbeat reached the connection
+}
+
+// LobbyState is the lobby data shared through the lobby store (everything but locks and timers)
+type LobbyState struct {
 	Code        string
 	Host        string
 	Players     map[string]*Player      // playerID -> Player
 	Scores      map[string]*PlayerScore // playerID -> PlayerScore (persistent)
 	CurrentGame *Game                   // nil when in lobby
-	mu          sync.RWMutex
-	sseClients  map[chan SSEMessage]string // channel -> playerID
+	Mode        GameMode                // ruleset used for the next game
+	Options     map[string]bool         // mode-specific settings chosen by the host
+	History     []*GameRecord           // finished games, oldest first
+	Tokens      map[string]string       // SHA-256 of an API bearer token (hex) -> playerID
+	CreatedAt   time.Time               // zero for lobbies saved before it was recorded
+	Revision    int64                   // bumped by shared stores on every write
 }
 
 // SSEMessage represents a message sent via Server-Sent Events
 type SSEMessage struct {
+	ID    int64  // Per-lobby event ID sent as the SSE "id:" field (0 = none)
 	Event string // Event type (e.g., "player-update", "nav-redirect")
 	Data  string // HTML content or data to send
 }
@@ -39,29 +68,99 @@ func (l *Lobby) RUnlock() {
 	l.mu.RUnlock()
 }
 
-// GetSSEClients returns a copy of the SSE clients map (must be called with lock held)
-func (l *Lobby) GetSSEClients() map[chan SSEMessage]string {
-	clients := make(map[chan SSEMessage]string, len(l.sseClients))
-	for k, v := range l.sseClients {
-		clients[k] = v
+// AfterSave queues fn to run once the current change is stored (must be called with lock held)
+// Shared stores may apply a change more than once, so side effects of a change belong here.
+func (l *Lobby) AfterSave(fn func()) {
+	l.afterSave = append(l.afterSave, fn)
+}
+
+// TakeAfterSave returns and clears the work queued with AfterSave
+func (l *Lobby) TakeAfterSave() []func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fns := l.afterSave
+	l.afterSave = nil
+	return fns
+}
+
+// HumanCount returns the number of non-bot players (must be called with lock held)
+func (l *Lobby) HumanCount() int {
+	count := 0
+	for _, p := range l.Players {
+		if !p.IsBot {
+			count++
+		}
 	}
-	return clients
+	return count
+}
+
+// Touch records activity on the lobby (safe without the lock)
+func (l *Lobby) Touch() {
+	l.lastActivity.Store(time.Now().UnixNano())
 }
 
-// AddSSEClient adds a new SSE client to the lobby
-func (l *Lobby) AddSSEClient(client chan SSEMessage, playerID string) {
+// TouchAt records activity seen at t elsewhere (e.g. on another instance), keeping the latest (safe without the lock)
+func (l *Lobby) TouchAt(t time.Time) {
+	for {
+		old := l.lastActivity.Load()
+		if t.UnixNano() <= old || l.lastActivity.CompareAndSwap(old, t.UnixNano()) {
+			return
+		}
+	}
+}
+
+// LastActivity returns when the lobby was last touched (safe without the lock)
+func (l *Lobby) LastActivity() time.Time {
+	return time.Unix(0, l.lastActivity.Load())
+}
+
+// AddSSEClient registers a live connection for a player (safe without the lock)
+func (l *Lobby) AddSSEClient(client chan SSEMessage, playerID, transport string) {
+	now := time.Now()
+	l.clientsMu.Lock()
+	defer l.clientsMu.Unlock()
 	if l.sseClients == nil {
-		l.sseClients = make(map[chan SSEMessage]string)
+		l.sseClients = make(map[chan SSEMessage]*SSEClient)
 	}
-	l.sseClients[client] = playerID
+	l.sseClients[client] = &SSEClient{PlayerID: playerID, Transport: transport, ConnectedAt: now, LastSeen: now}
 }
 
-// RemoveSSEClient removes an SSE client from the lobby
+// RemoveSSEClient unregisters a live connection (safe without the lock)
 func (l *Lobby) RemoveSSEClient(client chan SSEMessage) {
+	l.clientsMu.Lock()
+	defer l.clientsMu.Unlock()
 	delete(l.sseClients, client)
 }
 
-// SSEClientCount returns the number of connected SSE clients
-func (l *Lobby) SSEClientCount() int {
-	return len(l.sseClients)
+// MarkSSEClientSeen records that a write to a live connection went through (safe without the lock)
+func (l *Lobby) MarkSSEClientSeen(client chan SSEMessage) {
+	l.clientsMu.Lock()
+	defer l.clientsMu.Unlock()
+	if c, ok := l.sseClients[client]; ok {
+		c.LastSeen = time.Now()
+	}
+}
+
+// SSEClients returns a snapshot of the lobby's live connections on this instance (safe without the lock)
+func (l *Lobby) SSEClients() []SSEClient {
+	l.clientsMu.Lock()
+	defer l.clientsMu.Unlock()
+	clients := make([]SSEClient, 0, len(l.sseClients))
+	for _, c := range l.sseClients {
+		clients = append(clients, *c)
+	}
+	return clients
+}
+
+// PlayerLastSeen returns when any live connection of a player was last reached (safe without the lock)
+// ok is false when the player has no connection to this instance
+func (l *Lobby) PlayerLastSeen(playerID string) (seen time.Time, ok bool) {
+	l.clientsMu.Lock()
+	defer l.clientsMu.Unlock()
+	for _, c := range l.sseClients {
+		if c.PlayerID == playerID && c.LastSeen.After(seen) {
+			seen, ok = c.LastSeen, true
+		}
+	}
+	return seen, ok
 }
@@ -4,16 +4,20 @@ package models
 type PlayerScore struct {
 	GamesWon  int
 	GamesLost int
+	Rating    Rating // skill rating within the lobby
 }
 
 // Player represents a player in the lobby
 type Player struct {
-	ID   string
-	Name string
+	ID    string
+	Name  string
+	IsBot bool // headless player driven by the server
 }
 
 // GamePlayerInfo contains game-specific player information
 type GamePlayerInfo struct {
 	Challenge string
 	IsSpy     bool
+	Word      string // Undercover only: the word this player received
+	IsMrWhite bool   // Undercover only: player received no word
 }
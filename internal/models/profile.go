@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Profile is an optional, claimable identity that collects stats across lobbies
+// Browsers are linked to a profile through their player_id cookie; the recovery code links new ones
+type Profile struct {
+	ID           string
+	Name         string   // display name, suggested when joining a lobby
+	RecoveryHash string   // SHA-256 of the recovery code (the code itself is never stored)
+	PlayerIDs    []string // player_id cookies linked to this profile
+	CreatedAt    time.Time
+	Stats        ProfileStats
+}
+
+// ProfileStats are lifetime totals over every finished game a profile took part in
+type ProfileStats struct {
+	GamesPlayed   int
+	SpyGames      int // games on the hidden team (spy, undercover or Mr. White)
+	SpyWins       int
+	InnocentGames int
+	InnocentWins  int
+	Votes         int    // ballots cast, counting every tie-break round
+	CorrectVotes  int    // ballots cast for a member of the hidden team
+	Rating        Rating // skill rating across all lobbies
+	LastPlayedAt  time.Time
+}
+
+// SpyWinRate is the share of hidden-team games won (0 when none were played)
+func (s ProfileStats) SpyWinRate() float64 {
+	return ratio(s.SpyWins, s.SpyGames)
+}
+
+// InnocentWinRate is the share of innocent games won (0 when none were played)
+func (s ProfileStats) InnocentWinRate() float64 {
+	return ratio(s.InnocentWins, s.InnocentGames)
+}
+
+// CorrectVoteRate is the share of ballots cast for a member of the hidden team (0 without votes)
+func (s ProfileStats) CorrectVoteRate() float64 {
+	return ratio(s.CorrectVotes, s.Votes)
+}
+
+// ratio returns n/d, or 0 when d is 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultRating is the rating of a player who has not played a role yet
+const DefaultRating = 1000
+
+// Rating is an Elo-style skill rating, kept separately for each side of the game
+type Rating struct {
+	Spy      RoleRating // games on the hidden team (spy, undercover or Mr. White)
+	Innocent RoleRating
+}
+
+// RoleRating is the rating for one role and how many games it is based on
+type RoleRating struct {
+	Value float64
+	Games int
+}
+
+// Current returns the rating, or DefaultRating before the first game
+func (r RoleRating) Current() float64 {
+	if r.Games == 0 {
+		return DefaultRating
+	}
+	return r.Value
+}
+
+// String formats the rating for display ("–" before the first game)
+func (r RoleRating) String() string {
+	if r.Games == 0 {
+		return "–"
+	}
+	return fmt.Sprint(int(math.Round(r.Value)))
+}
+
+// For returns the rating of the side a player was on
+func (r Rating) For(impostor bool) RoleRating {
+	if impostor {
+		return r.Spy
+	}
+	return r.Innocent
+}
+
+// Apply adds a rating change for a game played on the given side
+func (r *Rating) Apply(impostor bool, delta float64) {
+	role := &r.Innocent
+	if impostor {
+		role = &r.Spy
+	}
+	role.Value = role.Current() + delta
+	role.Games++
+}
@@ -0,0 +1,7 @@
+package models
+
+// WordPair represents two related words for the Undercover mode
+type WordPair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
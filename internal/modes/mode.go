@@ -0,0 +1,67 @@
+package modes
+
+import "github.com/aaronzipp/you-are-officially-sus/internal/models"
+
+// Mode defines the rules of a game variant
+//
+// The handlers own the shared phase flow (ready checks, role reveal, playing,
+// voting); a Mode only decides who gets which secret, who is on the hidden
+// team, and how the finished game is presented.
+type Mode interface {
+	// ID is the stable identifier stored on lobbies and games
+	ID() models.GameMode
+	// Name is shown in the host's mode selector
+	Name() string
+	// Options lists host-toggleable settings stored in Lobby.Options
+	Options() []Option
+	// Text returns the mode-specific copy used on the phase pages
+	Text() Text
+	// Setup deals roles and secrets for a new game (playerIDs are already shuffled)
+	Setup(g *models.Game, lobby *models.Lobby, playerIDs []string)
+	// SecretView returns what a player privately sees on their role card
+	SecretView(g *models.Game, playerID string) SecretView
+	// Secrets lists what the game was played with, e.g. the location, for the admin view,
+	// the game history and the export (most important first)
+	Secrets(g *models.Game) []models.Secret
+	// RoleName is a player's role as shown in the game history
+	RoleName(g *models.Game, playerID string) string
+	// IsImpostor reports whether a player is on the hidden team
+	IsImpostor(g *models.Game, playerID string) bool
+	// Forfeits reports whether a player leaving hands the win to the innocents
+	Forfeits(g *models.Game, playerID string) bool
+	// InnocentsWin evaluates the final vote (votedOut is empty on a tie)
+	InnocentsWin(g *models.Game, votedOut string) bool
+	// ResultsTemplate is the template used to render the results page
+	ResultsTemplate() string
+	// ResultsView returns mode-specific data exposed to the results template as .Details
+	ResultsView(g *models.Game, lobby *models.Lobby) any
+}
+
+// Option is a boolean lobby setting offered by a mode
+type Option struct {
+	Key   string
+	Label string
+}
+
+// Text holds the mode-specific copy for the phase pages
+type Text struct {
+	VoteQuestion string // heading on the voting page
+	VotePrompt   string // instruction above the voting buttons
+	RevealTip    string // reminder on the role reveal page
+	PlayTip      string // footer on the play page
+}
+
+// Detail is a labelled value shown on a role card
+type Detail struct {
+	Label string
+	Value string
+}
+
+// SecretView is the private information a player sees about their role
+type SecretView struct {
+	Title    string   // heading on the role card
+	Details  []Detail // secrets shown on the role card, most important first
+	Hint     string   // optional note below the details
+	Reminder Detail   // repeated on the play page
+	Spy      bool     // style the role card as the hidden team
+}
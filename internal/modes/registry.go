@@ -0,0 +1,56 @@
+package modes
+
+import (
+	"fmt"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// Registry holds the game modes available to lobbies
+// Modes are registered at startup; the registry is read-only afterwards
+type Registry struct {
+	modes map[models.GameMode]Mode
+	order []Mode
+}
+
+// NewRegistry creates a registry with the given modes (the first one is the default)
+func NewRegistry(modes ...Mode) *Registry {
+	r := &Registry{modes: make(map[models.GameMode]Mode)}
+	for _, m := range modes {
+		r.Register(m)
+	}
+	return r
+}
+
+// Register adds a mode to the registry
+func (r *Registry) Register(m Mode) {
+	if _, dup := r.modes[m.ID()]; dup {
+		panic(fmt.Sprintf("modes: mode %q registered twice", m.ID()))
+	}
+	r.modes[m.ID()] = m
+	r.order = append(r.order, m)
+}
+
+// Lookup returns the mode with the given ID
+func (r *Registry) Lookup(id models.GameMode) (Mode, bool) {
+	m, ok := r.modes[id]
+	return m, ok
+}
+
+// Get returns the mode with the given ID, falling back to the default mode
+func (r *Registry) Get(id models.GameMode) Mode {
+	if m, ok := r.modes[id]; ok {
+		return m
+	}
+	return r.Default()
+}
+
+// Default returns the first registered mode
+func (r *Registry) Default() Mode {
+	return r.order[0]
+}
+
+// All returns every registered mode in registration order
+func (r *Registry) All() []Mode {
+	return r.order
+}
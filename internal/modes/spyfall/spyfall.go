@@ -0,0 +1,140 @@
+// Package spyfall implements the classic ruleset: everyone but the spy knows the location
+package spyfall
+
+import (
+	"math/rand"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+)
+
+// ID identifies the Spyfall mode on lobbies and games
+const ID models.GameMode = "spyfall"
+
+// Keys of Game.Secrets
+const (
+	SecretLocation = "location"
+	SecretCategory = "category" // the hint the spy gets
+)
+
+// Mode is the Spyfall game mode
+type Mode struct {
+	locations  []models.Location
+	challenges []string
+}
+
+// New creates the Spyfall mode from the location and challenge datasets
+func New(locations []models.Location, challenges []string) *Mode {
+	return &Mode{locations: locations, challenges: challenges}
+}
+
+// ID implements modes.Mode
+func (m *Mode) ID() models.GameMode { return ID }
+
+// Name implements modes.Mode
+func (m *Mode) Name() string { return "Spyfall (find the spy)" }
+
+// Options implements modes.Mode
+func (m *Mode) Options() []modes.Option { return nil }
+
+// Text implements modes.Mode
+func (m *Mode) Text() modes.Text {
+	return modes.Text{
+		VoteQuestion: "Who is the spy?",
+		VotePrompt:   "Select who you think is the spy:",
+		RevealTip:    "Remember your role and challenge, then confirm to continue!",
+		PlayTip:      "Ask questions and try to complete your challenge!",
+	}
+}
+
+// Setup picks the location, the spy and a challenge for every player
+func (m *Mode) Setup(g *models.Game, lobby *models.Lobby, playerIDs []string) {
+	location := m.locations[rand.Intn(len(m.locations))]
+	g.Secrets = map[string]string{SecretLocation: location.Word, SecretCategory: location.Categories[0]}
+	g.SpyID = playerIDs[0]
+	g.SpyName = lobby.Players[g.SpyID].Name
+
+	shuffledChallenges := make([]string, len(m.challenges))
+	copy(shuffledChallenges, m.challenges)
+	rand.Shuffle(len(shuffledChallenges), func(i, j int) {
+		shuffledChallenges[i], shuffledChallenges[j] = shuffledChallenges[j], shuffledChallenges[i]
+	})
+
+	for i, id := range playerIDs {
+		g.PlayerInfo[id] = &models.GamePlayerInfo{
+			Challenge: shuffledChallenges[i%len(shuffledChallenges)],
+			IsSpy:     id == g.SpyID,
+		}
+	}
+}
+
+// SecretView shows the location (or only its category to the spy) and the challenge
+func (m *Mode) SecretView(g *models.Game, playerID string) modes.SecretView {
+	info := g.PlayerInfo[playerID]
+	if info == nil {
+		return modes.SecretView{}
+	}
+	challenge := modes.Detail{Label: "Your Challenge:", Value: info.Challenge}
+	if info.IsSpy {
+		return modes.SecretView{
+			Title:    "You are the SPY",
+			Details:  []modes.Detail{{Label: "Category:", Value: g.Secrets[SecretCategory]}, challenge},
+			Reminder: challenge,
+			Spy:      true,
+		}
+	}
+	return modes.SecretView{
+		Title:    "You are NOT the spy",
+		Details:  []modes.Detail{{Label: "Location:", Value: g.Secrets[SecretLocation]}, challenge},
+		Reminder: challenge,
+	}
+}
+
+// Secrets implements modes.Mode
+func (m *Mode) Secrets(g *models.Game) []models.Secret {
+	return []models.Secret{{Key: SecretLocation, Label: "Location", Value: g.Secrets[SecretLocation]}}
+}
+
+// RoleName implements modes.Mode
+func (m *Mode) RoleName(g *models.Game, playerID string) string {
+	if m.IsImpostor(g, playerID) {
+		return "Spy"
+	}
+	return "Innocent"
+}
+
+// IsImpostor implements modes.Mode
+func (m *Mode) IsImpostor(g *models.Game, playerID string) bool {
+	return playerID != "" && playerID == g.SpyID
+}
+
+// Forfeits implements modes.Mode
+func (m *Mode) Forfeits(g *models.Game, playerID string) bool {
+	return playerID == g.SpyID
+}
+
+// InnocentsWin implements modes.Mode
+func (m *Mode) InnocentsWin(g *models.Game, votedOut string) bool {
+	return m.IsImpostor(g, votedOut)
+}
+
+// ResultsTemplate implements modes.Mode
+func (m *Mode) ResultsTemplate() string { return "results.html" }
+
+// Results is the Spyfall-specific data on the results page
+type Results struct {
+	Location   string
+	Challenges map[string]string
+}
+
+// ResultsView implements modes.Mode
+func (m *Mode) ResultsView(g *models.Game, lobby *models.Lobby) any {
+	challenges := make(map[string]string)
+	for pid, info := range g.PlayerInfo {
+		challenges[pid] = info.Challenge
+	}
+	return Results{
+		Location:   g.Secrets[SecretLocation],
+		Challenges: challenges,
+	}
+}
@@ -0,0 +1,181 @@
+// Package undercover implements the word-pair ruleset: civilians share a word,
+// the undercover player gets a related one and an optional Mr. White gets none
+//
+// The civilians win only by voting out the undercover player, or when the undercover leaves.
+// Mr. White plays for the hidden team but only muddies the waters: voting him out does not
+// catch the undercover, and his leaving does not end the game.
+package undercover
+
+import (
+	"math/rand"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+)
+
+const (
+	// ID identifies the Undercover mode on lobbies and games
+	ID models.GameMode = "undercover"
+
+	// OptionMrWhite deals an additional Mr. White who receives no word
+	OptionMrWhite = "mr_white"
+
+	// MrWhiteMinPlayers is the minimum number of players before a Mr. White is dealt
+	MrWhiteMinPlayers = 4
+
+	// Keys of Game.Secrets
+	SecretCivilianWord   = "civilian_word"
+	SecretUndercoverWord = "undercover_word"
+)
+
+// Mode is the Undercover game mode
+type Mode struct {
+	pairs []models.WordPair
+}
+
+// New creates the Undercover mode from the word pair dataset
+func New(pairs []models.WordPair) *Mode {
+	return &Mode{pairs: pairs}
+}
+
+// ID implements modes.Mode
+func (m *Mode) ID() models.GameMode { return ID }
+
+// Name implements modes.Mode
+func (m *Mode) Name() string { return "Undercover (word pairs)" }
+
+// Options implements modes.Mode
+func (m *Mode) Options() []modes.Option {
+	return []modes.Option{{Key: OptionMrWhite, Label: "Mr. White (needs 4+ players)"}}
+}
+
+// Text implements modes.Mode
+func (m *Mode) Text() modes.Text {
+	return modes.Text{
+		VoteQuestion: "Who is undercover?",
+		VotePrompt:   "Select who you think is undercover:",
+		RevealTip:    "Remember your word, then confirm to continue!",
+		PlayTip:      "Take turns describing your word and find the odd one out!",
+	}
+}
+
+// Setup picks the word pair, the undercover player and optionally Mr. White
+func (m *Mode) Setup(g *models.Game, lobby *models.Lobby, playerIDs []string) {
+	pair := m.pairs[rand.Intn(len(m.pairs))]
+
+	// Randomize which side of the pair the civilians get
+	civilianWord, undercoverWord := pair.A, pair.B
+	if rand.Intn(2) == 0 {
+		civilianWord, undercoverWord = pair.B, pair.A
+	}
+	g.Secrets = map[string]string{SecretCivilianWord: civilianWord, SecretUndercoverWord: undercoverWord}
+
+	g.SpyID = playerIDs[0]
+	g.SpyName = lobby.Players[g.SpyID].Name
+	if lobby.Options[OptionMrWhite] && len(playerIDs) >= MrWhiteMinPlayers {
+		g.MrWhiteID = playerIDs[1]
+		g.MrWhiteName = lobby.Players[g.MrWhiteID].Name
+	}
+
+	for _, id := range playerIDs {
+		info := &models.GamePlayerInfo{IsSpy: id == g.SpyID}
+		switch id {
+		case g.SpyID:
+			info.Word = undercoverWord
+		case g.MrWhiteID:
+			info.IsMrWhite = true
+		default:
+			info.Word = civilianWord
+		}
+		g.PlayerInfo[id] = info
+	}
+}
+
+// SecretView shows the player's word; the undercover player cannot tell they are undercover
+func (m *Mode) SecretView(g *models.Game, playerID string) modes.SecretView {
+	info := g.PlayerInfo[playerID]
+	if info == nil {
+		return modes.SecretView{}
+	}
+	if info.IsMrWhite {
+		return modes.SecretView{
+			Title:    "You are Mr. White",
+			Details:  []modes.Detail{{Label: "Your word:", Value: "You have no word. Listen closely and blend in!"}},
+			Reminder: modes.Detail{Label: "Your Word:", Value: "None - you are Mr. White"},
+			Spy:      true,
+		}
+	}
+	return modes.SecretView{
+		Title:    "Your secret word",
+		Details:  []modes.Detail{{Label: "Word:", Value: info.Word}},
+		Hint:     "Someone may have a slightly different word. Describe yours without giving it away!",
+		Reminder: modes.Detail{Label: "Your Word:", Value: info.Word},
+	}
+}
+
+// Secrets implements modes.Mode
+func (m *Mode) Secrets(g *models.Game) []models.Secret {
+	return []models.Secret{
+		{Key: SecretCivilianWord, Label: "Civilian word", Value: g.Secrets[SecretCivilianWord]},
+		{Key: SecretUndercoverWord, Label: "Undercover word", Value: g.Secrets[SecretUndercoverWord]},
+	}
+}
+
+// RoleName implements modes.Mode
+func (m *Mode) RoleName(g *models.Game, playerID string) string {
+	switch playerID {
+	case g.SpyID:
+		return "Undercover"
+	case g.MrWhiteID:
+		return "Mr. White"
+	default:
+		return "Civilian"
+	}
+}
+
+// IsImpostor reports whether the player is the undercover or Mr. White
+func (m *Mode) IsImpostor(g *models.Game, playerID string) bool {
+	if playerID == "" {
+		return false
+	}
+	return playerID == g.SpyID || playerID == g.MrWhiteID
+}
+
+// Forfeits implements modes.Mode (Mr. White leaving does not end the game)
+func (m *Mode) Forfeits(g *models.Game, playerID string) bool {
+	return playerID == g.SpyID
+}
+
+// InnocentsWin reports whether the civilians voted out the undercover player (Mr. White does not count)
+func (m *Mode) InnocentsWin(g *models.Game, votedOut string) bool {
+	return votedOut != "" && votedOut == g.SpyID
+}
+
+// ResultsTemplate implements modes.Mode
+func (m *Mode) ResultsTemplate() string { return "results_undercover.html" }
+
+// Results is the Undercover-specific data on the results page
+type Results struct {
+	CivilianWord    string
+	UndercoverWord  string
+	MrWhite         *models.Player
+	MrWhiteVotedOut bool // the vote caught Mr. White, so the undercover survived
+}
+
+// ResultsView implements modes.Mode
+func (m *Mode) ResultsView(g *models.Game, lobby *models.Lobby) any {
+	// Mr. White may have left, so fall back to the stored name
+	var mrWhite *models.Player
+	if g.MrWhiteID != "" {
+		mrWhite = lobby.Players[g.MrWhiteID]
+		if mrWhite == nil {
+			mrWhite = &models.Player{ID: g.MrWhiteID, Name: g.MrWhiteName}
+		}
+	}
+	return Results{
+		CivilianWord:    g.Secrets[SecretCivilianWord],
+		UndercoverWord:  g.Secrets[SecretUndercoverWord],
+		MrWhite:         mrWhite,
+		MrWhiteVotedOut: g.Result != nil && g.MrWhiteID != "" && g.Result.MostVoted == g.MrWhiteID,
+	}
+}
@@ -0,0 +1,73 @@
+package sse
+
+import "github.com/aaronzipp/you-are-officially-sus/internal/models"
+
+// Message is an event published to a room, optionally addressed to a single player
+type Message struct {
+	ID       int64  `json:"id"` // assigned by the broker, increasing per room
+	Room     string `json:"room"`
+	PlayerID string `json:"player_id,omitempty"` // empty = every client in the room
+	Format   string `json:"format"`              // FormatHTML or FormatJSON
+	Event    string `json:"event"`
+	Data     string `json:"data"`
+}
+
+// Broker fans published messages out to the SSE clients of every instance
+// Subscriptions are always local; Publish reaches subscribers on all instances sharing the broker
+type Broker interface {
+	// Publish numbers a message, records it for replay and delivers it to the room's subscribers
+	Publish(msg Message) error
+	// Replay returns the recorded messages of a room after afterID that are addressed to playerID in format
+	// ok is false when the missed messages are no longer available; the client must then resync
+	Replay(room, playerID, format string, afterID int64) (msgs []Message, ok bool)
+	// Subscribe registers a local client channel for a player in a room, receiving messages in format
+	Subscribe(room, playerID, format string, client chan models.SSEMessage)
+	// Unsubscribe removes a local client channel
+	Unsubscribe(room string, client chan models.SSEMessage)
+	// ClientCount returns the number of local clients in a room
+	ClientCount(room string) int
+	// Close releases the broker's resources
+	Close() error
+}
+
+// MemoryBroker delivers messages within the current process (single instance)
+type MemoryBroker struct {
+	hub *hub
+	log *replayLog
+}
+
+// NewMemoryBroker creates an in-process broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{hub: newHub(), log: newReplayLog()}
+}
+
+// Publish numbers a message and delivers it to local subscribers
+func (b *MemoryBroker) Publish(msg Message) error {
+	b.hub.deliver(b.log.append(msg))
+	return nil
+}
+
+// Replay returns the recorded messages of a room after afterID for playerID in format
+func (b *MemoryBroker) Replay(room, playerID, format string, afterID int64) ([]Message, bool) {
+	return b.log.since(room, playerID, format, afterID)
+}
+
+// Subscribe registers a local client channel
+func (b *MemoryBroker) Subscribe(room, playerID, format string, client chan models.SSEMessage) {
+	b.hub.add(room, playerID, format, client)
+}
+
+// Unsubscribe removes a local client channel
+func (b *MemoryBroker) Unsubscribe(room string, client chan models.SSEMessage) {
+	b.hub.remove(room, client)
+}
+
+// ClientCount returns the number of local clients in a room
+func (b *MemoryBroker) ClientCount(room string) int {
+	return b.hub.count(room)
+}
+
+// Close is a no-op for the in-process broker
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -9,4 +9,5 @@ const (
 	EventVoteCount      = "vote-count-voting"
 	EventHostChanged    = "host-changed"
 	EventErrorMessage   = "error-message"
+	EventServerNotice   = "server-notice"
 )
@@ -0,0 +1,85 @@
+package sse
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// hub tracks the SSE connections held by this process and delivers messages to them
+type hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[chan models.SSEMessage]*subscriber // room -> channel -> subscriber
+}
+
+// subscriber is a local client channel and the queue feeding it
+type subscriber struct {
+	playerID string
+	format   string
+	queue    *clientQueue
+}
+
+// newHub creates an empty hub
+func newHub() *hub {
+	return &hub{rooms: make(map[string]map[chan models.SSEMessage]*subscriber)}
+}
+
+// add registers a client channel for a player in a room, receiving messages in format
+func (h *hub) add(room, playerID, format string, client chan models.SSEMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.rooms[room]
+	if clients == nil {
+		clients = make(map[chan models.SSEMessage]*subscriber)
+		h.rooms[room] = clients
+	}
+
+	// Warn if the same player has multiple SSE connections
+	dup := 0
+	for _, sub := range clients {
+		if sub.playerID == playerID {
+			dup++
+		}
+	}
+	if dup > 0 {
+		slog.Warn("sse: player opened additional connections", "lobby", room, "player", playerID, "additional", dup)
+	}
+	clients[client] = &subscriber{playerID: playerID, format: format, queue: newClientQueue(room, format, client)}
+}
+
+// remove unregisters a client channel
+func (h *hub) remove(room string, client chan models.SSEMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub, ok := h.rooms[room][client]; ok {
+		sub.queue.close()
+	}
+	delete(h.rooms[room], client)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// count returns the number of local connections in a room
+func (h *hub) count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// deliver queues a message for the matching local clients of its room without blocking
+func (h *hub) deliver(msg Message) {
+	out := models.SSEMessage{ID: msg.ID, Event: msg.Event, Data: msg.Data}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	queued := 0
+	for _, sub := range h.rooms[msg.Room] {
+		if sub.format == msg.Format && (msg.PlayerID == "" || sub.playerID == msg.PlayerID) {
+			sub.queue.push(out)
+			queued++
+		}
+	}
+	slog.Debug("sse: event queued", "lobby", msg.Room, "event", msg.Event, "clients", queued)
+}
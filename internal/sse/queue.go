@@ -0,0 +1,198 @@
+package sse
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// OverflowPolicy decides what happens when a client's queue is full
+type OverflowPolicy string
+
+const (
+	// DropOldest discards the oldest queued message to make room
+	DropOldest OverflowPolicy = "drop-oldest"
+	// Coalesce replaces a queued message of the same event (every event swaps a whole fragment,
+	// so only the newest one matters) and drops the oldest message when none matches
+	// JSON events are deltas that must not be merged, so JSON queues disconnect instead
+	Coalesce OverflowPolicy = "coalesce"
+	// Disconnect ends the client's stream; the EventSource reconnects and resyncs via replay
+	Disconnect OverflowPolicy = "disconnect"
+)
+
+// ParseOverflowPolicy validates a policy name ("" selects Coalesce)
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case "":
+		return Coalesce, nil
+	case DropOldest, Coalesce, Disconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown SSE overflow policy %q (want drop-oldest, coalesce or disconnect)", s)
+}
+
+// overflowPolicy applies to clients connecting after it was set
+var overflowPolicy atomic.Value // OverflowPolicy
+
+// SetOverflowPolicy sets the policy for full client queues (call before serving requests)
+func SetOverflowPolicy(p OverflowPolicy) {
+	overflowPolicy.Store(p)
+}
+
+// currentPolicy returns the configured overflow policy
+func currentPolicy() OverflowPolicy {
+	if p, ok := overflowPolicy.Load().(OverflowPolicy); ok {
+		return p
+	}
+	return Coalesce
+}
+
+// Delivery counters since startup
+var (
+	droppedCount      atomic.Uint64
+	coalescedCount    atomic.Uint64
+	disconnectedCount atomic.Uint64
+	sendTimeoutCount  atomic.Uint64
+)
+
+// DeliveryStats counts messages that never reached a slow client
+type DeliveryStats struct {
+	Dropped      uint64 // messages discarded from full queues
+	Coalesced    uint64 // messages replaced by a newer message of the same event
+	Disconnected uint64 // clients disconnected because their queue was full
+	SendTimeouts uint64 // writes to a client that ran into the write deadline
+}
+
+// Stats returns the delivery counters of this instance
+func Stats() DeliveryStats {
+	return DeliveryStats{
+		Dropped:      droppedCount.Load(),
+		Coalesced:    coalescedCount.Load(),
+		Disconnected: disconnectedCount.Load(),
+		SendTimeouts: sendTimeoutCount.Load(),
+	}
+}
+
+// clientQueue is the bounded queue of one client, drained into its channel by a writer goroutine
+// Enqueueing never blocks, so one stalled client cannot delay delivery to the others
+type clientQueue struct {
+	client   chan models.SSEMessage
+	room     string
+	policy   OverflowPolicy
+	capacity int
+
+	mu     sync.Mutex
+	msgs   []models.SSEMessage
+	wake   chan struct{} // signals the writer that msgs is not empty
+	stop   chan struct{} // closed when the client unsubscribes
+	kicked bool          // the queue overflowed under the Disconnect policy
+}
+
+// policyFor returns the overflow policy for a client of the given format
+func policyFor(format string) OverflowPolicy {
+	p := currentPolicy()
+	if p == Coalesce && format != FormatHTML {
+		// A later vote_cast does not replace an earlier one; let the client resync via replay
+		return Disconnect
+	}
+	return p
+}
+
+// newClientQueue creates a queue for client and starts its writer
+func newClientQueue(room, format string, client chan models.SSEMessage) *clientQueue {
+	q := &clientQueue{
+		client:   client,
+		room:     room,
+		policy:   policyFor(format),
+		capacity: game.SSEBufferSize,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// push queues a message, applying the overflow policy when the queue is full
+func (q *clientQueue) push(msg models.SSEMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.kicked {
+		return
+	}
+	if len(q.msgs) >= q.capacity {
+		switch q.policy {
+		case Disconnect:
+			q.kicked = true
+			q.msgs = nil
+			disconnectedCount.Add(1)
+			slog.Warn("sse: client queue overflowed, disconnecting it", "lobby", q.room)
+			q.signal()
+			return
+		case Coalesce:
+			if i := slices.IndexFunc(q.msgs, func(m models.SSEMessage) bool { return m.Event == msg.Event }); i >= 0 {
+				// Re-append rather than replace in place so event IDs stay increasing
+				q.msgs = slices.Delete(q.msgs, i, i+1)
+				coalescedCount.Add(1)
+				break
+			}
+			fallthrough
+		default:
+			q.msgs = q.msgs[1:]
+			droppedCount.Add(1)
+		}
+		slog.Debug("sse: client queue full", "lobby", q.room, "policy", q.policy, "event", msg.Event)
+	}
+	q.msgs = append(q.msgs, msg)
+	q.signal()
+}
+
+// signal wakes the writer without blocking (caller must hold q.mu)
+func (q *clientQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the writer once the client unsubscribed
+func (q *clientQueue) close() {
+	close(q.stop)
+}
+
+// run moves queued messages into the client channel until the client unsubscribes
+// A disconnected client gets its channel closed, which ends its SSE handler
+func (q *clientQueue) run() {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.stop:
+			return
+		}
+		for {
+			q.mu.Lock()
+			if q.kicked {
+				q.mu.Unlock()
+				close(q.client)
+				return
+			}
+			if len(q.msgs) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			msg := q.msgs[0]
+			q.msgs = q.msgs[1:]
+			q.mu.Unlock()
+
+			select {
+			case q.client <- msg:
+			case <-q.stop:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package sse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// newTestQueue returns a queue without a writer, so pushed messages stay queued
+func newTestQueue(policy OverflowPolicy, capacity int) *clientQueue {
+	return &clientQueue{
+		client:   make(chan models.SSEMessage),
+		room:     "ABCD",
+		policy:   policy,
+		capacity: capacity,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+func TestClientQueuePush(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy OverflowPolicy
+		events []string // pushed in order with IDs 1, 2, ...
+		want   []int64  // IDs left in the queue
+		kicked bool
+	}{
+		{
+			name:   "not full",
+			policy: Disconnect,
+			events: []string{"lobby", "players", "lobby"},
+			want:   []int64{1, 2, 3},
+		},
+		{
+			name:   "coalesce replaces the same event",
+			policy: Coalesce,
+			events: []string{"players", "lobby", "timer", "lobby"},
+			want:   []int64{1, 3, 4},
+		},
+		{
+			name:   "coalesce drops the oldest without a match",
+			policy: Coalesce,
+			events: []string{"players", "lobby", "timer", "votes"},
+			want:   []int64{2, 3, 4},
+		},
+		{
+			name:   "drop oldest",
+			policy: DropOldest,
+			events: []string{"players", "lobby", "timer", "lobby"},
+			want:   []int64{2, 3, 4},
+		},
+		{
+			name:   "disconnect",
+			policy: Disconnect,
+			events: []string{"players", "lobby", "timer", "lobby", "votes"},
+			kicked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(tt.policy, 3)
+			for i, event := range tt.events {
+				q.push(models.SSEMessage{ID: int64(i + 1), Event: event})
+			}
+			if q.kicked != tt.kicked {
+				t.Fatalf("got kicked %v, want %v", q.kicked, tt.kicked)
+			}
+			var got []int64
+			for _, m := range q.msgs {
+				got = append(got, m.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got queued IDs %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFor(t *testing.T) {
+	t.Cleanup(func() { SetOverflowPolicy(Coalesce) })
+	tests := []struct {
+		configured OverflowPolicy
+		format     string
+		want       OverflowPolicy
+	}{
+		{Coalesce, FormatHTML, Coalesce},
+		{Coalesce, FormatJSON, Disconnect}, // JSON events are deltas and must not be merged
+		{DropOldest, FormatHTML, DropOldest},
+		{DropOldest, FormatJSON, DropOldest},
+		{Disconnect, FormatJSON, Disconnect},
+	}
+	for _, tt := range tests {
+		SetOverflowPolicy(tt.configured)
+		if got := policyFor(tt.format); got != tt.want {
+			t.Errorf("policyFor(%s) with %s configured = %s, want %s", tt.format, tt.configured, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker relays messages between instances through Redis pub/sub
+// Every instance publishes to "<prefix><room>" and pattern-subscribes to all rooms,
+// then delivers to its own local clients. Event IDs come from the "<prefix>seq:<room>"
+// counter and recent events are kept in the "<prefix>log:<room>" list for replay.
+type RedisBroker struct {
+	hub    *hub
+	client redis.UniversalClient
+	prefix string
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// NewRedisBroker subscribes to all room channels under prefix using client
+// Any client works, e.g. one pointed at a throwaway local Redis (or an in-process stand-in) in tests
+func NewRedisBroker(client redis.UniversalClient, prefix string) (*RedisBroker, error) {
+	ctx := context.Background()
+	pubsub := client.PSubscribe(ctx, prefix+"*")
+	// Wait for the subscription so messages published right after startup are not lost
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribing to %s*: %w", prefix, err)
+	}
+
+	b := &RedisBroker{
+		hub:    newHub(),
+		client: client,
+		prefix: prefix,
+		pubsub: pubsub,
+		done:   make(chan struct{}),
+	}
+	go b.receive()
+	return b, nil
+}
+
+// receive delivers messages from Redis to local clients until the subscription is closed
+func (b *RedisBroker) receive() {
+	defer close(b.done)
+	for m := range b.pubsub.Channel() {
+		var msg Message
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			slog.Warn("sse: dropping malformed message", "channel", m.Channel, "err", err)
+			continue
+		}
+		b.hub.deliver(msg)
+	}
+}
+
+// publishScript numbers, records and publishes a message in one step, so messages of a room
+// are logged and delivered in ID order even when several instances publish at once
+// KEYS: seq, log, channel; ARGV: message JSON after its leading "id" field, replay buffer size, TTL in seconds
+var publishScript = redis.NewScript(`
+local id = redis.call("INCR", KEYS[1])
+local payload = '{"id":' .. id .. ',' .. ARGV[1]
+redis.call("RPUSH", KEYS[2], payload)
+redis.call("LTRIM", KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call("EXPIRE", KEYS[2], ARGV[3])
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+redis.call("PUBLISH", KEYS[3], payload)
+return id
+`)
+
+// Publish numbers a message, records it and sends it to every instance (including this one) via Redis
+func (b *RedisBroker) Publish(msg Message) error {
+	ctx := context.Background()
+	msg.ID = 0 // numbered by the script
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	rest, ok := strings.CutPrefix(string(payload), `{"id":0,`)
+	if !ok {
+		return fmt.Errorf("unexpected message encoding %.20s", payload)
+	}
+	keys := []string{b.prefix + "seq:" + msg.Room, b.prefix + "log:" + msg.Room, b.prefix + msg.Room}
+	return publishScript.Run(ctx, b.client, keys, rest, game.SSEReplayBuffer, int(replayTTL.Seconds())).Err()
+}
+
+// Replay returns the recorded messages of a room after afterID for playerID in format
+func (b *RedisBroker) Replay(room, playerID, format string, afterID int64) ([]Message, bool) {
+	ctx := context.Background()
+	seq, err := b.client.Get(ctx, b.prefix+"seq:"+room).Int64()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Error("sse: reading event sequence", "lobby", room, "err", err)
+		}
+		return nil, false
+	}
+	payloads, err := b.client.LRange(ctx, b.prefix+"log:"+room, 0, -1).Result()
+	if err != nil {
+		slog.Error("sse: reading event log", "lobby", room, "err", err)
+		return nil, false
+	}
+
+	buffered := make([]Message, 0, len(payloads))
+	for _, payload := range payloads {
+		var msg Message
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			continue
+		}
+		buffered = append(buffered, msg)
+	}
+	return missedMessages(buffered, seq, playerID, format, afterID)
+}
+
+// Subscribe registers a local client channel
+func (b *RedisBroker) Subscribe(room, playerID, format string, client chan models.SSEMessage) {
+	b.hub.add(room, playerID, format, client)
+}
+
+// Unsubscribe removes a local client channel
+func (b *RedisBroker) Unsubscribe(room string, client chan models.SSEMessage) {
+	b.hub.remove(room, client)
+}
+
+// ClientCount returns the number of local clients in a room
+func (b *RedisBroker) ClientCount(room string) int {
+	return b.hub.count(room)
+}
+
+// Close ends the subscription (the Redis client is owned by the caller)
+func (b *RedisBroker) Close() error {
+	err := b.pubsub.Close()
+	<-b.done
+	return err
+}
@@ -0,0 +1,102 @@
+package sse
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisBrokers returns two brokers sharing one in-process Redis, like two instances
+func newTestRedisBrokers(t *testing.T) (*RedisBroker, *RedisBroker) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	newBroker := func() *RedisBroker {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		b, err := NewRedisBroker(client, "test:sse:")
+		if err != nil {
+			t.Fatalf("NewRedisBroker: %v", err)
+		}
+		t.Cleanup(func() {
+			b.Close()
+			client.Close()
+		})
+		return b
+	}
+	return newBroker(), newBroker()
+}
+
+func TestRedisBrokerOrdersConcurrentPublishes(t *testing.T) {
+	a, b := newTestRedisBrokers(t)
+
+	const perBroker = 30
+	client := make(chan models.SSEMessage, 2*perBroker)
+	b.Subscribe("ABCD", "p1", FormatJSON, client)
+	defer b.Unsubscribe("ABCD", client)
+
+	// Both instances publish to the same room at once
+	var wg sync.WaitGroup
+	for i, broker := range []*RedisBroker{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range perBroker {
+				msg := Message{Room: "ABCD", Format: FormatJSON, Event: "test", Data: fmt.Sprintf("%d-%d", i, n)}
+				if err := broker.Publish(msg); err != nil {
+					t.Errorf("Publish: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Delivered in ID order without gaps
+	for want := int64(1); want <= 2*perBroker; want++ {
+		select {
+		case msg := <-client:
+			if msg.ID != want {
+				t.Fatalf("got message %d, want %d", msg.ID, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("message %d was not delivered", want)
+		}
+	}
+
+	// The replay log is in the same order
+	missed, ok := a.Replay("ABCD", "p1", FormatJSON, 2*perBroker-5)
+	if !ok || len(missed) != 5 {
+		t.Fatalf("Replay returned %d messages (ok=%v), want 5", len(missed), ok)
+	}
+	for i, msg := range missed {
+		if want := int64(2*perBroker - 4 + i); msg.ID != want {
+			t.Fatalf("replayed message %d has ID %d, want %d", i, msg.ID, want)
+		}
+	}
+}
+
+func TestRedisBrokerReplay(t *testing.T) {
+	a, b := newTestRedisBrokers(t)
+
+	a.Publish(Message{Room: "ABCD", Format: FormatJSON, Event: "everyone"})
+	a.Publish(Message{Room: "ABCD", Format: FormatJSON, PlayerID: "p2", Event: "private"})
+	a.Publish(Message{Room: "ABCD", Format: FormatHTML, Event: "html"})
+	a.Publish(Message{Room: "ABCD", Format: FormatJSON, Event: "later"})
+
+	// Only messages for the player and format after the given ID come back
+	missed, ok := b.Replay("ABCD", "p1", FormatJSON, 0)
+	if !ok || len(missed) != 2 || missed[0].Event != "everyone" || missed[1].Event != "later" {
+		t.Fatalf("Replay returned %+v (ok=%v)", missed, ok)
+	}
+
+	// A client ahead of the log must resync
+	if _, ok := b.Replay("ABCD", "p1", FormatJSON, 10); ok {
+		t.Fatal("Replay after an unknown ID succeeded")
+	}
+	if _, ok := b.Replay("WXYZ", "p1", FormatJSON, 1); ok {
+		t.Fatal("Replay of a room without events succeeded")
+	}
+}
@@ -0,0 +1,76 @@
+package sse
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/events"
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// Formats a client can receive messages in
+const (
+	FormatHTML = "html" // HTMX partials for browser pages
+	FormatJSON = "json" // events.Envelope documents for API clients
+)
+
+// Renderer turns a domain event into the messages of one format
+// Messages with a PlayerID only reach that player
+type Renderer interface {
+	Render(lobby *models.Lobby, ev events.Event) []Message
+}
+
+// RendererFunc adapts a function to a Renderer
+type RendererFunc func(lobby *models.Lobby, ev events.Event) []Message
+
+// Render calls f
+func (f RendererFunc) Render(lobby *models.Lobby, ev events.Event) []Message {
+	return f(lobby, ev)
+}
+
+// renderers by format; JSON is built in, HTML is registered by the web handlers
+var renderers = map[string]Renderer{FormatJSON: RendererFunc(renderJSON)}
+
+// SetRenderer registers the renderer of a format (call before serving requests)
+func SetRenderer(format string, r Renderer) {
+	renderers[format] = r
+}
+
+// Publish renders events in every format and hands the messages to the broker
+// Must not be called while holding the lobby lock, since renderers read the lobby
+func Publish(lobby *models.Lobby, evs ...events.Event) {
+	for _, ev := range evs {
+		slog.Debug("sse: publishing", "lobby", lobby.Code, "event", ev.Type())
+		for format, r := range renderers {
+			for _, msg := range r.Render(lobby, ev) {
+				msg.Room, msg.Format = lobby.Code, format
+				publish(msg)
+			}
+		}
+	}
+}
+
+// Render renders an event for one client without publishing it, e.g. the snapshot a client starts with
+func Render(lobby *models.Lobby, format, playerID string, ev events.Event) []models.SSEMessage {
+	r, ok := renderers[format]
+	if !ok {
+		return nil
+	}
+	var out []models.SSEMessage
+	for _, msg := range r.Render(lobby, ev) {
+		if msg.PlayerID == "" || msg.PlayerID == playerID {
+			out = append(out, models.SSEMessage{Event: msg.Event, Data: msg.Data})
+		}
+	}
+	return out
+}
+
+// renderJSON encodes an event as a single message named after its type
+func renderJSON(lobby *models.Lobby, ev events.Event) []Message {
+	data, err := events.Marshal(lobby.Code, ev, time.Now())
+	if err != nil {
+		slog.Error("sse: encoding event", "event", ev.Type(), "err", err)
+		return nil
+	}
+	return []Message{{Event: ev.Type(), Data: string(data)}}
+}
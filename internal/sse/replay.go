@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/game"
+)
+
+// replayTTL is how long a room's event log is kept after its last event
+const replayTTL = time.Hour
+
+// replayLog numbers the events of each room and keeps the most recent ones for Last-Event-ID replay
+type replayLog struct {
+	mu        sync.Mutex
+	rooms     map[string]*roomLog
+	lastSweep time.Time
+}
+
+// roomLog is the event sequence and recent events of one room
+type roomLog struct {
+	seq     int64     // ID of the last event
+	msgs    []Message // oldest first, at most game.SSEReplayBuffer
+	updated time.Time
+}
+
+// newReplayLog creates an empty replay log
+func newReplayLog() *replayLog {
+	return &replayLog{rooms: make(map[string]*roomLog), lastSweep: time.Now()}
+}
+
+// append assigns the next ID of the room to msg and records it
+func (l *replayLog) append(msg Message) Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > replayTTL {
+		// Drop the logs of rooms that went quiet (lobbies closed or expired)
+		for room, rl := range l.rooms {
+			if now.Sub(rl.updated) > replayTTL {
+				delete(l.rooms, room)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	rl := l.rooms[msg.Room]
+	if rl == nil {
+		rl = &roomLog{}
+		l.rooms[msg.Room] = rl
+	}
+	rl.seq++
+	rl.updated = now
+	msg.ID = rl.seq
+	rl.msgs = append(rl.msgs, msg)
+	if len(rl.msgs) > game.SSEReplayBuffer {
+		rl.msgs = rl.msgs[len(rl.msgs)-game.SSEReplayBuffer:]
+	}
+	return msg
+}
+
+// since returns the room's events after afterID addressed to playerID in format
+func (l *replayLog) since(room, playerID, format string, afterID int64) ([]Message, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rl := l.rooms[room]
+	if rl == nil {
+		return nil, false
+	}
+	return missedMessages(rl.msgs, rl.seq, playerID, format, afterID)
+}
+
+// missedMessages picks the events after afterID for playerID in format from a room's buffered events
+// ok is false when the buffer no longer covers afterID, or afterID is from before a restart
+func missedMessages(buffered []Message, seq int64, playerID, format string, afterID int64) ([]Message, bool) {
+	if afterID > seq {
+		return nil, false
+	}
+	if len(buffered) > 0 && buffered[0].ID > afterID+1 {
+		return nil, false
+	}
+	var missed []Message
+	for _, msg := range buffered {
+		if msg.ID > afterID && msg.Format == format && (msg.PlayerID == "" || msg.PlayerID == playerID) {
+			missed = append(missed, msg)
+		}
+	}
+	return missed, true
+}
@@ -0,0 +1,82 @@
+package sse
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"sync/atomic"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// Transports a client can receive live updates over
+const (
+	TransportSSE       = "sse"
+	TransportWebSocket = "websocket"
+)
+
+// Subscription is one client's feed of a lobby's live messages, shared by the SSE and WebSocket transports
+// C delivers the messages in order and is closed when the client fell too far behind (see OverflowPolicy)
+type Subscription struct {
+	C        <-chan models.SSEMessage
+	PlayerID string
+	Format   string // FormatHTML or FormatJSON
+
+	lobby     *models.Lobby
+	ch        chan models.SSEMessage
+	transport string
+}
+
+// Live clients of this instance by transport
+var (
+	sseClients       atomic.Int64
+	webSocketClients atomic.Int64
+)
+
+// Connections returns the number of live clients of this instance using transport
+func Connections(transport string) int64 {
+	return connections(transport).Load()
+}
+
+// connections returns the counter of a transport
+func connections(transport string) *atomic.Int64 {
+	if transport == TransportWebSocket {
+		return &webSocketClients
+	}
+	return &sseClients
+}
+
+// Subscribe registers a live client for a player in a lobby, receiving messages in format
+// Close must be called once the client is gone
+func Subscribe(lobby *models.Lobby, playerID, transport, format string) *Subscription {
+	// Unbuffered: the broker queues messages for this client and applies the overflow policy
+	ch := make(chan models.SSEMessage)
+	broker.Subscribe(lobby.Code, playerID, format, ch)
+	lobby.AddSSEClient(ch, playerID, transport)
+	lobby.Touch()
+	connections(transport).Add(1)
+	return &Subscription{C: ch, PlayerID: playerID, Format: format, lobby: lobby, ch: ch, transport: transport}
+}
+
+// Seen records that a write to the client went through
+func (s *Subscription) Seen() {
+	s.lobby.MarkSSEClientSeen(s.ch)
+}
+
+// Failed records a write to the client that failed; the transport drops the connection afterwards
+// Writes that ran into the write deadline are counted in Stats
+func (s *Subscription) Failed(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		sendTimeoutCount.Add(1)
+	}
+}
+
+// Close unregisters the client
+func (s *Subscription) Close() {
+	broker.Unsubscribe(s.lobby.Code, s.ch)
+	s.lobby.RemoveSSEClient(s.ch)
+	connections(s.transport).Add(-1)
+	s.lobby.Touch() // idle time counts from the last disconnect
+	slog.Debug("sse: client removed", "lobby", s.lobby.Code, "player", s.PlayerID, "clients", broker.ClientCount(s.lobby.Code))
+}
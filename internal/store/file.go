@@ -0,0 +1,186 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// snapshotVersion is bumped whenever the snapshot layout changes incompatibly
+const snapshotVersion = 1
+
+// DefaultSnapshotInterval is how often the file store writes lobbies to disk
+const DefaultSnapshotInterval = 5 * time.Second
+
+// snapshot is the on-disk format of the file store
+type snapshot struct {
+	Version int                        `json:"version"`
+	SavedAt time.Time                  `json:"saved_at"`
+	Lobbies map[string]json.RawMessage `json:"lobbies"`
+}
+
+// FileStore is an in-memory store that periodically snapshots all lobbies to a JSON file
+// Lobbies, scores and running games are restored from the snapshot on startup
+type FileStore struct {
+	*MemoryStore
+	path string
+
+	writeMu  sync.Mutex // serializes snapshot writes
+	lastData []byte     // lobbies of the last written snapshot, to skip unchanged writes
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFileStore loads the snapshot at path (if any) and starts writing snapshots every interval
+func NewFileStore(path string, interval time.Duration) (*FileStore, error) {
+	s := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	go s.loop(interval)
+	return s, nil
+}
+
+// load restores lobbies from the snapshot file
+func (s *FileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("store: no snapshot, starting empty", "path", s.path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parsing snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("snapshot %s has version %d, expected %d", s.path, snap.Version, snapshotVersion)
+	}
+
+	for code, raw := range snap.Lobbies {
+		lobby := &models.Lobby{}
+		if err := json.Unmarshal(raw, lobby); err != nil {
+			slog.Warn("store: skipping lobby from snapshot", "lobby", code, "err", err)
+			continue
+		}
+		if lobby.Players == nil {
+			lobby.Players = make(map[string]*models.Player)
+		}
+		if lobby.Scores == nil {
+			lobby.Scores = make(map[string]*models.PlayerScore)
+		}
+		if lobby.Options == nil {
+			lobby.Options = make(map[string]bool)
+		}
+		s.MemoryStore.Set(code, lobby)
+	}
+	slog.Info("store: restored lobbies", "count", len(snap.Lobbies), "path", s.path, "saved_at", snap.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// loop writes snapshots until Close is called
+func (s *FileStore) loop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				slog.Error("store: snapshot failed", "err", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush writes all lobbies to disk now
+// The file is replaced atomically so a crash mid-write never leaves a truncated snapshot
+func (s *FileStore) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	lobbies := make(map[string]json.RawMessage)
+	for code, lobby := range s.MemoryStore.All() {
+		lobby.RLock()
+		raw, err := json.Marshal(lobby)
+		lobby.RUnlock()
+		if err != nil {
+			return fmt.Errorf("encoding lobby %s: %w", code, err)
+		}
+		lobbies[code] = raw
+	}
+
+	// Skip the write when nothing changed since the last snapshot
+	body, err := json.Marshal(lobbies)
+	if err != nil {
+		return fmt.Errorf("encoding lobbies: %w", err)
+	}
+	if bytes.Equal(body, s.lastData) {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot{Version: snapshotVersion, SavedAt: time.Now(), Lobbies: lobbies})
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.lastData = body
+	return nil
+}
+
+// Close stops the snapshot loop and writes a final snapshot
+func (s *FileStore) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+	return nil
+}
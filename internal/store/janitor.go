@@ -0,0 +1,107 @@
+package store
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// Janitor periodically expires lobbies that have no connected clients and no recent activity
+type Janitor struct {
+	store    LobbyStore
+	clients  func(code string) int
+	ttl      time.Duration
+	interval time.Duration
+	onExpire func(code string, lobby *models.Lobby)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewJanitor creates a janitor that expires lobbies idle for longer than ttl
+// clients reports the connected SSE clients of a lobby; onExpire (optional) is called after
+// a lobby was removed, e.g. to redirect stragglers that connected during the sweep
+func NewJanitor(store LobbyStore, ttl time.Duration, clients func(code string) int, onExpire func(code string, lobby *models.Lobby)) *Janitor {
+	// Check a few times per TTL, but not more often than every second or less than every minute
+	interval := min(max(ttl/4, time.Second), time.Minute)
+	return &Janitor{
+		store:    store,
+		clients:  clients,
+		ttl:      ttl,
+		interval: interval,
+		onExpire: onExpire,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the janitor in the background until Stop is called
+func (j *Janitor) Start() {
+	slog.Info("janitor: expiring idle lobbies", "ttl", j.ttl, "interval", j.interval)
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.Sweep()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the background loop
+func (j *Janitor) Stop() {
+	close(j.stop)
+	<-j.done
+}
+
+// sharedStore is implemented by stores shared between instances
+// Every instance reports the lobbies its clients are connected to, and one instance per round sweeps.
+type sharedStore interface {
+	// Refresh marks a lobby as active for every instance
+	Refresh(code string)
+	// AcquireSweep reports whether this instance sweeps for the next d
+	AcquireSweep(d time.Duration) bool
+}
+
+// Sweep expires idle lobbies once and returns how many were removed
+func (j *Janitor) Sweep() int {
+	cutoff := time.Now().Add(-j.ttl)
+	lobbies := j.store.All()
+
+	if shared, ok := j.store.(sharedStore); ok {
+		// Connections to this instance keep a lobby alive on the others
+		for code, lobby := range lobbies {
+			if j.clients(code) > 0 {
+				shared.Refresh(code)
+				lobby.Touch()
+			}
+		}
+		if !shared.AcquireSweep(j.interval) {
+			return 0
+		}
+	}
+
+	expired := make(map[string]*models.Lobby)
+	for code, lobby := range lobbies {
+		if j.clients(code) == 0 && lobby.LastActivity().Before(cutoff) {
+			j.store.Delete(code)
+			expired[code] = lobby
+		}
+	}
+
+	for code, lobby := range expired {
+		if j.onExpire != nil {
+			j.onExpire(code, lobby)
+		}
+	}
+	if len(expired) > 0 {
+		slog.Info("janitor: expired idle lobbies", "expired", len(expired), "remaining", len(lobbies)-len(expired))
+	}
+	return len(expired)
+}
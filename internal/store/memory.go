@@ -1,50 +1,86 @@
 package store
 
 import (
+	"maps"
 	"sync"
 
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
 )
 
-// LobbyStore manages lobby storage
-type LobbyStore struct {
+// MemoryStore keeps lobbies in a process-local map (lost on restart)
+type MemoryStore struct {
 	lobbies map[string]*models.Lobby
 	mu      sync.RWMutex
 }
 
-// NewLobbyStore creates a new lobby store
-func NewLobbyStore() *LobbyStore {
-	return &LobbyStore{
+// NewMemoryStore creates a new in-memory lobby store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		lobbies: make(map[string]*models.Lobby),
 	}
 }
 
-// Get retrieves a lobby by code
-func (s *LobbyStore) Get(code string) (*models.Lobby, bool) {
+// Get retrieves a lobby by code and marks it as active
+func (s *MemoryStore) Get(code string) (*models.Lobby, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	lobby, exists := s.lobbies[code]
+	if exists {
+		lobby.Touch()
+	}
 	return lobby, exists
 }
 
-// Set stores a lobby
-func (s *LobbyStore) Set(code string, lobby *models.Lobby) {
+// Set stores a lobby and marks it as active
+func (s *MemoryStore) Set(code string, lobby *models.Lobby) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	lobby.Touch()
 	s.lobbies[code] = lobby
 }
 
+// Update applies change to a lobby and stores it
+func (s *MemoryStore) Update(lobby *models.Lobby, change func() error) error {
+	s.mu.RLock()
+	current := s.lobbies[lobby.Code]
+	s.mu.RUnlock()
+	if current != lobby {
+		return ErrLobbyNotFound // closed or replaced in the meantime
+	}
+
+	lobby.TakeAfterSave()
+	if err := change(); err != nil {
+		lobby.TakeAfterSave()
+		return err
+	}
+	s.Set(lobby.Code, lobby)
+	runAfterSave(lobby)
+	return nil
+}
+
 // Delete removes a lobby
-func (s *LobbyStore) Delete(code string) {
+func (s *MemoryStore) Delete(code string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.lobbies, code)
 }
 
 // Exists checks if a lobby code exists
-func (s *LobbyStore) Exists(code string) bool {
+func (s *MemoryStore) Exists(code string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	_, exists := s.lobbies[code]
 	return exists
 }
+
+// All returns a copy of the lobby map
+func (s *MemoryStore) All() map[string]*models.Lobby {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.lobbies)
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// ErrProfileNotFound is returned when a profile ID is unknown
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileStore manages player profiles
+// Profiles are returned as copies; changes go through Create, Link and Update
+type ProfileStore interface {
+	// Get retrieves a profile by ID
+	Get(id string) (*models.Profile, bool)
+	// ByPlayer retrieves the profile a player_id cookie is linked to
+	ByPlayer(playerID string) (*models.Profile, bool)
+	// ByRecoveryHash retrieves the profile with the given recovery code hash
+	ByRecoveryHash(hash string) (*models.Profile, bool)
+	// Create stores a new profile and links its PlayerIDs
+	Create(p *models.Profile) error
+	// Link attaches a player_id cookie to a profile, moving it off any previous profile
+	Link(profileID, playerID string) error
+	// Update applies fn to a profile atomically
+	Update(id string, fn func(p *models.Profile)) error
+	// Close persists any pending state and releases resources
+	Close() error
+}
+
+// MemoryProfileStore keeps profiles in process-local maps (lost on restart)
+type MemoryProfileStore struct {
+	mu         sync.RWMutex
+	profiles   map[string]*models.Profile
+	byPlayer   map[string]string // playerID -> profileID
+	byRecovery map[string]string // recovery hash -> profileID
+	revision   int64             // bumped on every change, used to skip unchanged snapshots
+}
+
+// NewMemoryProfileStore creates a new in-memory profile store
+func NewMemoryProfileStore() *MemoryProfileStore {
+	return &MemoryProfileStore{
+		profiles:   make(map[string]*models.Profile),
+		byPlayer:   make(map[string]string),
+		byRecovery: make(map[string]string),
+	}
+}
+
+// cloneProfile copies a profile so callers never share memory with the store
+func cloneProfile(p *models.Profile) *models.Profile {
+	cp := *p
+	cp.PlayerIDs = slices.Clone(p.PlayerIDs)
+	return &cp
+}
+
+// Get retrieves a profile by ID
+func (s *MemoryProfileStore) Get(id string) (*models.Profile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[id]
+	if !ok {
+		return nil, false
+	}
+	return cloneProfile(p), true
+}
+
+// ByPlayer retrieves the profile a player is linked to
+func (s *MemoryProfileStore) ByPlayer(playerID string) (*models.Profile, bool) {
+	s.mu.RLock()
+	id, ok := s.byPlayer[playerID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return s.Get(id)
+}
+
+// ByRecoveryHash retrieves the profile with the given recovery code hash
+func (s *MemoryProfileStore) ByRecoveryHash(hash string) (*models.Profile, bool) {
+	s.mu.RLock()
+	id, ok := s.byRecovery[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return s.Get(id)
+}
+
+// Create stores a new profile
+func (s *MemoryProfileStore) Create(p *models.Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.profiles[p.ID]; exists {
+		return errors.New("profile already exists")
+	}
+	s.put(cloneProfile(p))
+	return nil
+}
+
+// put stores a profile and indexes it (caller must hold the write lock)
+func (s *MemoryProfileStore) put(p *models.Profile) {
+	for _, playerID := range p.PlayerIDs {
+		s.unlink(playerID)
+		s.byPlayer[playerID] = p.ID
+	}
+	s.profiles[p.ID] = p
+	s.byRecovery[p.RecoveryHash] = p.ID
+	s.revision++
+}
+
+// unlink removes a player from the profile it is currently linked to (caller must hold the write lock)
+func (s *MemoryProfileStore) unlink(playerID string) {
+	id, ok := s.byPlayer[playerID]
+	if !ok {
+		return
+	}
+	delete(s.byPlayer, playerID)
+	if p, ok := s.profiles[id]; ok {
+		p.PlayerIDs = slices.DeleteFunc(p.PlayerIDs, func(pid string) bool { return pid == playerID })
+	}
+}
+
+// Link attaches a player to a profile
+func (s *MemoryProfileStore) Link(profileID, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.profiles[profileID]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if s.byPlayer[playerID] == profileID {
+		return nil
+	}
+	s.unlink(playerID)
+	p.PlayerIDs = append(p.PlayerIDs, playerID)
+	s.byPlayer[playerID] = profileID
+	s.revision++
+	return nil
+}
+
+// Update applies fn to a profile under the store lock
+// fn may change the name and stats; identity fields are restored afterwards
+func (s *MemoryProfileStore) Update(id string, fn func(p *models.Profile)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.profiles[id]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	cp := cloneProfile(p)
+	fn(cp)
+	cp.ID, cp.RecoveryHash, cp.PlayerIDs = p.ID, p.RecoveryHash, p.PlayerIDs
+	s.profiles[id] = cp
+	s.revision++
+	return nil
+}
+
+// all returns copies of every profile and the current revision
+func (s *MemoryProfileStore) all() ([]*models.Profile, int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	profiles := make([]*models.Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, cloneProfile(p))
+	}
+	return profiles, s.revision
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryProfileStore) Close() error {
+	return nil
+}
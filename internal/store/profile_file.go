@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// profileSnapshot is the on-disk format of the file profile store
+type profileSnapshot struct {
+	Version  int               `json:"version"`
+	SavedAt  time.Time         `json:"saved_at"`
+	Profiles []*models.Profile `json:"profiles"`
+}
+
+// FileProfileStore is an in-memory profile store that periodically writes all profiles to a JSON file
+type FileProfileStore struct {
+	*MemoryProfileStore
+	path string
+
+	writeMu      sync.Mutex // serializes snapshot writes
+	lastRevision int64      // revision of the last written snapshot, to skip unchanged writes
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFileProfileStore loads the profiles at path (if any) and starts writing them every interval
+func NewFileProfileStore(path string, interval time.Duration) (*FileProfileStore, error) {
+	s := &FileProfileStore{
+		MemoryProfileStore: NewMemoryProfileStore(),
+		path:               path,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	go s.loop(interval)
+	return s, nil
+}
+
+// load restores profiles from the snapshot file
+func (s *FileProfileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("store: no profile snapshot, starting empty", "path", s.path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading profile snapshot: %w", err)
+	}
+
+	var snap profileSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parsing profile snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("profile snapshot %s has version %d, expected %d", s.path, snap.Version, snapshotVersion)
+	}
+
+	s.mu.Lock()
+	for _, p := range snap.Profiles {
+		s.put(p)
+	}
+	s.lastRevision = s.revision
+	s.mu.Unlock()
+	slog.Info("store: restored profiles", "count", len(snap.Profiles), "path", s.path)
+	return nil
+}
+
+// loop writes snapshots until Close is called
+func (s *FileProfileStore) loop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				slog.Error("store: profile snapshot failed", "err", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush writes all profiles to disk now if anything changed since the last write
+func (s *FileProfileStore) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	profiles, revision := s.MemoryProfileStore.all()
+	if revision == s.lastRevision {
+		return nil
+	}
+
+	data, err := json.Marshal(profileSnapshot{Version: snapshotVersion, SavedAt: time.Now(), Profiles: profiles})
+	if err != nil {
+		return fmt.Errorf("encoding profile snapshot: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.lastRevision = revision
+	return nil
+}
+
+// Close stops the snapshot loop and writes a final snapshot
+func (s *FileProfileStore) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxTxRetries bounds the optimistic-locking retries of a profile write
+const maxTxRetries = 5
+
+// RedisProfileStore shares profiles between instances through Redis
+// Profiles are JSON under "<prefix>id:<id>" with "<prefix>player:<playerID>" and
+// "<prefix>recovery:<hash>" pointing at the profile ID. Writes use WATCH/MULTI so
+// concurrent stat updates from different instances never overwrite each other.
+type RedisProfileStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisProfileStore creates a profile store using client; profile keys never expire
+func NewRedisProfileStore(client redis.UniversalClient, prefix string) *RedisProfileStore {
+	return &RedisProfileStore{client: client, prefix: prefix}
+}
+
+func (s *RedisProfileStore) profileKey(id string) string      { return s.prefix + "id:" + id }
+func (s *RedisProfileStore) playerKey(playerID string) string { return s.prefix + "player:" + playerID }
+func (s *RedisProfileStore) recoveryKey(hash string) string   { return s.prefix + "recovery:" + hash }
+
+// load reads and decodes a profile with cmd (the client or a transaction)
+func (s *RedisProfileStore) load(ctx context.Context, cmd redis.Cmdable, id string) (*models.Profile, error) {
+	data, err := cmd.Get(ctx, s.profileKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &models.Profile{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("decoding profile %s: %w", id, err)
+	}
+	return p, nil
+}
+
+// Get retrieves a profile by ID
+func (s *RedisProfileStore) Get(id string) (*models.Profile, bool) {
+	p, err := s.load(context.Background(), s.client, id)
+	if err != nil {
+		if !errors.Is(err, ErrProfileNotFound) {
+			slog.Error("store: loading profile", "profile", id, "err", err)
+		}
+		return nil, false
+	}
+	return p, true
+}
+
+// lookup follows an index key to its profile
+func (s *RedisProfileStore) lookup(key string) (*models.Profile, bool) {
+	id, err := s.client.Get(context.Background(), key).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Error("store: looking up profile", "key", key, "err", err)
+		}
+		return nil, false
+	}
+	return s.Get(id)
+}
+
+// ByPlayer retrieves the profile a player is linked to
+func (s *RedisProfileStore) ByPlayer(playerID string) (*models.Profile, bool) {
+	return s.lookup(s.playerKey(playerID))
+}
+
+// ByRecoveryHash retrieves the profile with the given recovery code hash
+func (s *RedisProfileStore) ByRecoveryHash(hash string) (*models.Profile, bool) {
+	return s.lookup(s.recoveryKey(hash))
+}
+
+// Create stores a new profile and its index keys
+// Players already linked elsewhere keep a stale entry in their old profile's PlayerIDs
+func (s *RedisProfileStore) Create(p *models.Profile) error {
+	ctx := context.Background()
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding profile %s: %w", p.ID, err)
+	}
+	ok, err := s.client.SetNX(ctx, s.profileKey(p.ID), data, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("profile already exists")
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.recoveryKey(p.RecoveryHash), p.ID, 0)
+		for _, playerID := range p.PlayerIDs {
+			pipe.Set(ctx, s.playerKey(playerID), p.ID, 0)
+		}
+		return nil
+	})
+	return err
+}
+
+// Link attaches a player to a profile, removing it from the profile it was linked to
+func (s *RedisProfileStore) Link(profileID, playerID string) error {
+	ctx := context.Background()
+	txf := func(tx *redis.Tx) error {
+		oldID, err := tx.Get(ctx, s.playerKey(playerID)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if oldID == profileID {
+			return nil
+		}
+		p, err := s.load(ctx, tx, profileID)
+		if err != nil {
+			return err
+		}
+		p.PlayerIDs = append(p.PlayerIDs, playerID)
+
+		var old *models.Profile
+		if oldID != "" {
+			if err := tx.Watch(ctx, s.profileKey(oldID)).Err(); err != nil {
+				return err
+			}
+			old, err = s.load(ctx, tx, oldID)
+			if err != nil && !errors.Is(err, ErrProfileNotFound) {
+				return err
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if err := s.write(ctx, pipe, p); err != nil {
+				return err
+			}
+			if old != nil {
+				old.PlayerIDs = slices.DeleteFunc(old.PlayerIDs, func(pid string) bool { return pid == playerID })
+				if err := s.write(ctx, pipe, old); err != nil {
+					return err
+				}
+			}
+			pipe.Set(ctx, s.playerKey(playerID), profileID, 0)
+			return nil
+		})
+		return err
+	}
+	return s.watch(ctx, txf, s.playerKey(playerID), s.profileKey(profileID))
+}
+
+// Update applies fn to a profile atomically
+// fn may change the name and stats; identity fields are restored afterwards
+func (s *RedisProfileStore) Update(id string, fn func(p *models.Profile)) error {
+	ctx := context.Background()
+	txf := func(tx *redis.Tx) error {
+		p, err := s.load(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		updated := cloneProfile(p)
+		fn(updated)
+		updated.ID, updated.RecoveryHash, updated.PlayerIDs = p.ID, p.RecoveryHash, p.PlayerIDs
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return s.write(ctx, pipe, updated)
+		})
+		return err
+	}
+	return s.watch(ctx, txf, s.profileKey(id))
+}
+
+// write queues a profile write on pipe
+func (s *RedisProfileStore) write(ctx context.Context, pipe redis.Pipeliner, p *models.Profile) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding profile %s: %w", p.ID, err)
+	}
+	pipe.Set(ctx, s.profileKey(p.ID), data, 0)
+	return nil
+}
+
+// watch runs txf under WATCH on keys, retrying when another instance wrote them first
+func (s *RedisProfileStore) watch(ctx context.Context, txf func(tx *redis.Tx) error, keys ...string) error {
+	for range maxTxRetries {
+		err := s.client.Watch(ctx, txf, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("profile write kept conflicting after %d attempts", maxTxRetries)
+}
+
+// Close is a no-op; the Redis client is owned by the caller
+func (s *RedisProfileStore) Close() error {
+	return nil
+}
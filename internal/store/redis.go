@@ -0,0 +1,349 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxLobbyTxRetries bounds the optimistic-locking retries of a lobby write
+// Lobbies see many more simultaneous writes than profiles (every ready click and vote of a round).
+const maxLobbyTxRetries = 10
+
+// RedisStore shares lobbies between instances through Redis
+// Each lobby is stored as JSON under "<prefix><code>". Every instance keeps one local *Lobby
+// per code (so locks and bot timers keep working) and refreshes it whenever Redis holds a
+// newer revision. Writes are compare-and-set on the revision: Update re-applies a change on top of
+// the newer state when another instance wrote the lobby first.
+// Idle lobbies are expired by a Janitor; keys also expire after twice the idle TTL without activity
+// in case no instance is left to sweep.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration // idle TTL (0 = never expire)
+
+	mu      sync.Mutex
+	local   map[string]*models.Lobby
+	updates map[string]*sync.Mutex // serializes Update per lobby on this instance
+}
+
+// NewRedisStore creates a store using client for lobbies that expire after ttl without activity
+func NewRedisStore(client redis.UniversalClient, prefix string, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		client:  client,
+		prefix:  prefix,
+		ttl:     ttl,
+		local:   make(map[string]*models.Lobby),
+		updates: make(map[string]*sync.Mutex),
+	}
+}
+
+// Get loads a lobby from Redis and marks it as active
+func (s *RedisStore) Get(code string) (*models.Lobby, bool) {
+	ctx := context.Background()
+	var data []byte
+	var err error
+	if s.ttl > 0 {
+		data, err = s.client.GetEx(ctx, s.prefix+code, s.keyTTL()).Bytes()
+	} else {
+		data, err = s.client.Get(ctx, s.prefix+code).Bytes()
+	}
+	if errors.Is(err, redis.Nil) {
+		s.forget(code)
+		return nil, false
+	}
+	if err != nil {
+		slog.Error("store: loading lobby", "lobby", code, "err", err)
+		return nil, false
+	}
+
+	lobby, err := s.sync(code, data)
+	if err != nil {
+		slog.Error("store: decoding lobby", "lobby", code, "err", err)
+		return nil, false
+	}
+	lobby.Touch()
+	return lobby, true
+}
+
+// sync returns the local lobby for code, replacing its state when data holds a newer revision
+// Must not be called while holding the lobby lock
+func (s *RedisStore) sync(code string, data []byte) (*models.Lobby, error) {
+	var state models.LobbyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	lobby, exists := s.local[code]
+	if !exists {
+		lobby = &models.Lobby{LobbyState: state}
+		s.local[code] = lobby
+	}
+	s.mu.Unlock()
+
+	if exists {
+		lobby.Lock()
+		if state.Revision > lobby.Revision {
+			lobby.LobbyState = state
+		}
+		lobby.Unlock()
+	}
+	return lobby, nil
+}
+
+// forget drops the local copy of a lobby
+func (s *RedisStore) forget(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.local, code)
+	delete(s.updates, code)
+}
+
+// Set writes a lobby to Redis unless another instance stored a newer revision of it
+// (must not be called while holding the lobby lock)
+func (s *RedisStore) Set(code string, lobby *models.Lobby) {
+	ctx := context.Background()
+	key := s.prefix + code
+	var stale []byte
+	txf := func(tx *redis.Tx) error {
+		stale = nil
+		stored, err := s.revision(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		lobby.RLock()
+		revision := lobby.Revision
+		lobby.RUnlock()
+		if stored > revision {
+			stale, err = tx.Get(ctx, key).Bytes()
+			return err
+		}
+		return s.write(ctx, tx, key, lobby, stored)
+	}
+
+	s.mu.Lock()
+	s.local[code] = lobby
+	s.mu.Unlock()
+	lobby.Touch()
+
+	if err := s.watch(ctx, txf, key); err != nil {
+		slog.Error("store: saving lobby", "lobby", code, "err", err)
+		return
+	}
+	if stale != nil {
+		// Someone else changed the lobby since it was loaded; keep their state
+		slog.Warn("store: dropped write of an outdated lobby", "lobby", code)
+		if _, err := s.sync(code, stale); err != nil {
+			slog.Error("store: decoding lobby", "lobby", code, "err", err)
+		}
+	}
+}
+
+// Update applies change to a lobby and writes it back unless another instance wrote it first,
+// in which case change runs again on the newer state
+func (s *RedisStore) Update(lobby *models.Lobby, change func() error) error {
+	ctx := context.Background()
+	code := lobby.Code
+	key := s.prefix + code
+
+	// Attempts reset the lobby to the stored state, so local updates must not interleave
+	s.mu.Lock()
+	updateMu, ok := s.updates[code]
+	if !ok {
+		updateMu = &sync.Mutex{}
+		s.updates[code] = updateMu
+	}
+	s.mu.Unlock()
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	var changeErr error
+	txf := func(tx *redis.Tx) error {
+		changeErr = nil
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrLobbyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var state models.LobbyState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("decoding lobby %s: %w", code, err)
+		}
+
+		// Start from the stored state, dropping whatever an earlier attempt changed
+		lobby.Lock()
+		lobby.LobbyState = state
+		lobby.Unlock()
+		lobby.TakeAfterSave()
+		if changeErr = change(); changeErr != nil {
+			return changeErr
+		}
+		return s.write(ctx, tx, key, lobby, state.Revision)
+	}
+
+	err := s.watch(ctx, txf, key)
+	switch {
+	case err == nil:
+		lobby.Touch()
+		runAfterSave(lobby)
+		return nil
+	case changeErr != nil:
+		lobby.TakeAfterSave()
+		return changeErr
+	case errors.Is(err, ErrLobbyNotFound):
+		s.forget(code)
+		return err
+	}
+	// The change may be applied locally but not stored; go back to what Redis holds
+	lobby.TakeAfterSave()
+	if data, getErr := s.client.Get(ctx, key).Bytes(); getErr == nil {
+		var state models.LobbyState
+		if json.Unmarshal(data, &state) == nil {
+			lobby.Lock()
+			lobby.LobbyState = state
+			lobby.Unlock()
+		}
+	}
+	return fmt.Errorf("saving lobby %s: %w", code, err)
+}
+
+// revision returns the stored revision of a lobby (0 when there is none)
+func (s *RedisStore) revision(ctx context.Context, tx *redis.Tx, key string) (int64, error) {
+	data, err := tx.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var state struct{ Revision int64 }
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, err
+	}
+	return state.Revision, nil
+}
+
+// write stores lobby as the revision after stored, failing if the key changed since tx started watching it
+func (s *RedisStore) write(ctx context.Context, tx *redis.Tx, key string, lobby *models.Lobby, stored int64) error {
+	lobby.Lock()
+	lobby.Revision = stored + 1
+	data, err := json.Marshal(&lobby.LobbyState)
+	lobby.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding lobby: %w", err)
+	}
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, s.keyTTL())
+		return nil
+	})
+	return err
+}
+
+// watch runs txf under WATCH on key, retrying when another instance wrote it first
+// Retries back off for a random moment so two busy instances do not keep colliding.
+func (s *RedisStore) watch(ctx context.Context, txf func(tx *redis.Tx) error, key string) error {
+	for attempt := range maxLobbyTxRetries {
+		err := s.client.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+		time.Sleep(rand.N(time.Duration(attempt+1) * 5 * time.Millisecond))
+	}
+	return fmt.Errorf("lobby write kept conflicting after %d attempts", maxLobbyTxRetries)
+}
+
+// Delete removes a lobby from Redis
+func (s *RedisStore) Delete(code string) {
+	s.forget(code)
+	if err := s.client.Del(context.Background(), s.prefix+code).Err(); err != nil {
+		slog.Error("store: deleting lobby", "lobby", code, "err", err)
+	}
+}
+
+// Exists checks if a lobby code exists in Redis
+func (s *RedisStore) Exists(code string) bool {
+	n, err := s.client.Exists(context.Background(), s.prefix+code).Result()
+	if err != nil {
+		slog.Error("store: checking lobby", "lobby", code, "err", err)
+		return false
+	}
+	return n > 0
+}
+
+// All loads every lobby stored in Redis
+// The activity of each lobby is taken from its key expiry, so it covers every instance.
+func (s *RedisStore) All() map[string]*models.Lobby {
+	ctx := context.Background()
+	lobbies := make(map[string]*models.Lobby)
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		code := key[len(s.prefix):]
+		var get *redis.StringCmd
+		var pttl *redis.DurationCmd
+		_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			get = pipe.Get(ctx, key)
+			pttl = pipe.PTTL(ctx, key)
+			return nil
+		})
+		if err != nil {
+			continue // expired or deleted since the scan
+		}
+		lobby, err := s.sync(code, []byte(get.Val()))
+		if err != nil {
+			slog.Error("store: decoding lobby", "lobby", code, "err", err)
+			continue
+		}
+		if s.ttl > 0 && pttl.Val() > 0 {
+			lobby.TouchAt(time.Now().Add(pttl.Val() - s.keyTTL()))
+		}
+		lobbies[code] = lobby
+	}
+	if err := iter.Err(); err != nil {
+		slog.Error("store: listing lobbies", "err", err)
+	}
+	return lobbies
+}
+
+// keyTTL is the expiry of lobby keys, refreshed on every read and write (0 = never expire)
+func (s *RedisStore) keyTTL() time.Duration {
+	return 2 * s.ttl
+}
+
+// Refresh marks a lobby as active for every instance, e.g. while clients are connected to it
+func (s *RedisStore) Refresh(code string) {
+	if s.ttl == 0 {
+		return
+	}
+	if err := s.client.Expire(context.Background(), s.prefix+code, s.keyTTL()).Err(); err != nil {
+		slog.Error("store: refreshing lobby", "lobby", code, "err", err)
+	}
+}
+
+// AcquireSweep takes the janitor lock for d, so only one instance expires lobbies at a time
+// The lock key sits outside the lobby prefix, so All never sees it.
+func (s *RedisStore) AcquireSweep(d time.Duration) bool {
+	ok, err := s.client.SetNX(context.Background(), strings.TrimSuffix(s.prefix, ":")+"-janitor", "1", d).Result()
+	if err != nil {
+		slog.Error("store: taking janitor lock", "err", err)
+		return false
+	}
+	return ok
+}
+
+// Close is a no-op; the Redis client is owned by the caller
+func (s *RedisStore) Close() error {
+	return nil
+}
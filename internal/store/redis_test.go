@@ -0,0 +1,200 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStores returns two stores sharing one in-process Redis, like two instances
+func newTestRedisStores(t *testing.T, ttl time.Duration) (*miniredis.Miniredis, *RedisStore, *RedisStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	newStore := func() *RedisStore {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return NewRedisStore(client, "test:lobby:", ttl)
+	}
+	return mr, newStore(), newStore()
+}
+
+func newTestLobby(code string) *models.Lobby {
+	return &models.Lobby{LobbyState: models.LobbyState{
+		Code:    code,
+		Host:    "host",
+		Players: map[string]*models.Player{"host": {ID: "host", Name: "Host"}},
+		Scores:  map[string]*models.PlayerScore{"host": {}},
+		Options: map[string]bool{},
+	}}
+}
+
+func TestRedisStoreSharesLobbies(t *testing.T) {
+	_, a, b := newTestRedisStores(t, time.Minute)
+
+	a.Set("ABCD", newTestLobby("ABCD"))
+	lobby, ok := b.Get("ABCD")
+	if !ok {
+		t.Fatal("lobby written by one store is missing in the other")
+	}
+	if lobby.Host != "host" || lobby.Revision != 1 {
+		t.Fatalf("got host %q revision %d, want host revision 1", lobby.Host, lobby.Revision)
+	}
+
+	err := b.Update(lobby, func() error {
+		lobby.Lock()
+		defer lobby.Unlock()
+		lobby.Players["p2"] = &models.Player{ID: "p2", Name: "Second"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	lobby, _ = a.Get("ABCD")
+	if _, ok := lobby.Players["p2"]; !ok {
+		t.Fatal("update from the other store was not picked up")
+	}
+
+	a.Delete("ABCD")
+	if b.Exists("ABCD") {
+		t.Fatal("deleted lobby still exists")
+	}
+	if err := b.Update(lobby, func() error { return nil }); err != ErrLobbyNotFound {
+		t.Fatalf("Update of a deleted lobby returned %v, want ErrLobbyNotFound", err)
+	}
+}
+
+func TestRedisStoreConcurrentUpdates(t *testing.T) {
+	_, a, b := newTestRedisStores(t, time.Minute)
+	a.Set("ABCD", newTestLobby("ABCD"))
+
+	// Both instances record votes at the same time; none may be lost
+	const votesPerStore = 25
+	var wg sync.WaitGroup
+	for i, s := range []*RedisStore{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lobby, ok := s.Get("ABCD")
+			if !ok {
+				t.Error("lobby not found")
+				return
+			}
+			for n := range votesPerStore {
+				voter := fmt.Sprintf("store%d-voter%d", i, n)
+				err := s.Update(lobby, func() error {
+					lobby.Lock()
+					defer lobby.Unlock()
+					lobby.Players[voter] = &models.Player{ID: voter, Name: voter}
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Update: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	lobby, _ := a.Get("ABCD")
+	if got, want := len(lobby.Players), 1+2*votesPerStore; got != want {
+		t.Fatalf("got %d players, want %d", got, want)
+	}
+	if lobby.Revision != 1+2*votesPerStore {
+		t.Fatalf("got revision %d, want %d", lobby.Revision, 1+2*votesPerStore)
+	}
+}
+
+func TestRedisStoreSetKeepsNewerRevision(t *testing.T) {
+	_, a, b := newTestRedisStores(t, time.Minute)
+	a.Set("ABCD", newTestLobby("ABCD"))
+
+	stale, _ := a.Get("ABCD")
+	fresh, _ := b.Get("ABCD")
+	b.Update(fresh, func() error {
+		fresh.Lock()
+		defer fresh.Unlock()
+		fresh.Host = "new-host"
+		return nil
+	})
+
+	// A plain write of the outdated copy must not clobber the newer state
+	a.Set("ABCD", stale)
+	lobby, _ := b.Get("ABCD")
+	if lobby.Host != "new-host" {
+		t.Fatalf("stale write replaced the lobby: host %q", lobby.Host)
+	}
+	if stale.Host != "new-host" {
+		t.Fatalf("stale copy was not refreshed: host %q", stale.Host)
+	}
+}
+
+func TestRedisStoreUpdateRunsSideEffectsOnce(t *testing.T) {
+	_, a, b := newTestRedisStores(t, time.Minute)
+	a.Set("ABCD", newTestLobby("ABCD"))
+	lobbyA, _ := a.Get("ABCD")
+	lobbyB, _ := b.Get("ABCD")
+
+	attempts, effects := 0, 0
+	err := a.Update(lobbyA, func() error {
+		attempts++
+		if attempts == 1 {
+			// Another instance saves while this change is in flight
+			b.Update(lobbyB, func() error { return nil })
+		}
+		lobbyA.Lock()
+		defer lobbyA.Unlock()
+		lobbyA.AfterSave(func() { effects++ })
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if attempts != 2 || effects != 1 {
+		t.Fatalf("got %d attempts and %d side effects, want 2 and 1", attempts, effects)
+	}
+}
+
+func TestRedisStoreJanitor(t *testing.T) {
+	mr, a, b := newTestRedisStores(t, time.Minute)
+	a.Set("IDLE", newTestLobby("IDLE"))
+	a.Set("LIVE", newTestLobby("LIVE"))
+
+	// Only one instance sweeps at a time
+	if !a.AcquireSweep(time.Minute) {
+		t.Fatal("first instance did not get the janitor lock")
+	}
+	if b.AcquireSweep(time.Minute) {
+		t.Fatal("second instance got the janitor lock while it was held")
+	}
+
+	var expired []string
+	onExpire := func(code string, _ *models.Lobby) { expired = append(expired, code) }
+
+	// Time passes; instance a has a client in LIVE but does not hold the lock, so it only reports it
+	mr.FastForward(90 * time.Second)
+	liveClients := func(code string) int {
+		if code == "LIVE" {
+			return 1
+		}
+		return 0
+	}
+	if n := NewJanitor(a, time.Minute, liveClients, onExpire).Sweep(); n != 0 {
+		t.Fatalf("instance without the lock expired %d lobbies", n)
+	}
+
+	// Instance b has no clients of its own and sweeps once the lock is free
+	mr.Del("test:lobby-janitor")
+	NewJanitor(b, time.Minute, func(string) int { return 0 }, onExpire).Sweep()
+
+	if len(expired) != 1 || expired[0] != "IDLE" {
+		t.Fatalf("expired %v, want [IDLE]", expired)
+	}
+	if a.Exists("IDLE") || !a.Exists("LIVE") {
+		t.Fatal("janitor removed the wrong lobbies")
+	}
+}
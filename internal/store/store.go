@@ -0,0 +1,39 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+)
+
+// ErrLobbyNotFound is returned by Update when the lobby was removed in the meantime
+var ErrLobbyNotFound = errors.New("lobby not found")
+
+// LobbyStore manages lobby storage
+// Lobbies are mutated in place under their own lock; backends decide how and when to persist them
+type LobbyStore interface {
+	// Get retrieves a lobby by code
+	Get(code string) (*models.Lobby, bool)
+	// Set stores a new lobby; later changes go through Update so shared backends see them
+	// (must not be called while holding the lobby lock)
+	Set(code string, lobby *models.Lobby)
+	// Update applies change to a lobby and stores it; change takes the lobby lock itself
+	// Shared backends run change again on top of the newer state when another instance changed the lobby first,
+	// so change must queue its side effects with lobby.AfterSave. Errors returned by change are passed through.
+	Update(lobby *models.Lobby, change func() error) error
+	// Delete removes a lobby
+	Delete(code string)
+	// Exists checks if a lobby code exists
+	Exists(code string) bool
+	// All returns every stored lobby keyed by code
+	All() map[string]*models.Lobby
+	// Close persists any pending state and releases resources
+	Close() error
+}
+
+// runAfterSave runs the work queued with lobby.AfterSave by a change that was stored
+func runAfterSave(lobby *models.Lobby) {
+	for _, fn := range lobby.TakeAfterSave() {
+		fn()
+	}
+}
@@ -1,40 +1,125 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/aaronzipp/you-are-officially-sus/internal/api"
 	"github.com/aaronzipp/you-are-officially-sus/internal/handlers"
+	"github.com/aaronzipp/you-are-officially-sus/internal/logging"
+	"github.com/aaronzipp/you-are-officially-sus/internal/metrics"
 	"github.com/aaronzipp/you-are-officially-sus/internal/models"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/spyfall"
+	"github.com/aaronzipp/you-are-officially-sus/internal/modes/undercover"
+	"github.com/aaronzipp/you-are-officially-sus/internal/sse"
 	"github.com/aaronzipp/you-are-officially-sus/internal/store"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
-	debug   bool
-	baseURL string
+	baseURL         string
+	storeKind       string
+	storeFile       string
+	profileFile     string
+	brokerKind      string
+	redisURL        string
+	idleTTL         time.Duration
+	shutdownTimeout time.Duration
+	sseOverflow     sse.OverflowPolicy
+	adminToken      string
 )
 
 func init() {
 	// Load .env file if it exists (ignore error if file doesn't exist)
 	_ = godotenv.Load()
 
-	// Enable DEBUG logs when DEBUG env var is set (non-empty)
-	debug = os.Getenv("DEBUG") != ""
+	// Structured logging: LOG_LEVEL (DEBUG=1 is short for debug), LOG_FORMAT and LOG_REDACT_NAMES
+	logLevel, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		fatal("Invalid LOG_LEVEL", err)
+	}
+	if os.Getenv("DEBUG") != "" {
+		logLevel = slog.LevelDebug
+	}
+	logger, err := logging.New(os.Stderr, logging.Options{
+		Level:       logLevel,
+		Format:      os.Getenv("LOG_FORMAT"),
+		RedactNames: os.Getenv("LOG_REDACT_NAMES") != "",
+	})
+	if err != nil {
+		fatal("Invalid LOG_FORMAT", err)
+	}
+	slog.SetDefault(logger)
 
 	// Read BASE_URL from environment (empty if not set)
 	baseURL = os.Getenv("BASE_URL")
+
+	// Lobby storage backend: "memory" (default), "file" or "redis"
+	storeKind = os.Getenv("STORE")
+	storeFile = os.Getenv("STORE_FILE")
+	if storeFile == "" {
+		storeFile = "lobbies.json"
+	}
+	// Profiles are kept in their own file with STORE=file
+	profileFile = os.Getenv("PROFILE_FILE")
+	if profileFile == "" {
+		profileFile = "profiles.json"
+	}
+
+	// SSE broker: "memory" (default, single instance) or "redis" (shared between instances)
+	brokerKind = os.Getenv("BROKER")
+	redisURL = os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379/0"
+	}
+
+	// Lobbies without connected clients are removed after this much inactivity (0 disables)
+	idleTTL = 30 * time.Minute
+	if v := os.Getenv("LOBBY_IDLE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			fatal("Invalid LOBBY_IDLE_TTL", err)
+		}
+		idleTTL = ttl
+	}
+
+	// How long a shutdown waits for open requests before closing them
+	shutdownTimeout = 10 * time.Second
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			fatal("Invalid SHUTDOWN_TIMEOUT", err)
+		}
+		shutdownTimeout = timeout
+	}
+
+	// What happens when a slow client's SSE queue is full: "coalesce" (default), "drop-oldest" or "disconnect"
+	policy, err := sse.ParseOverflowPolicy(os.Getenv("SSE_OVERFLOW"))
+	if err != nil {
+		fatal("Invalid SSE_OVERFLOW", err)
+	}
+	sseOverflow = policy
+
+	// Password of the /admin dashboard (disabled when empty)
+	adminToken = os.Getenv("ADMIN_TOKEN")
 }
 
 func main() {
 	// Load data
-	locations, challenges, err := loadData()
+	locations, challenges, wordPairs, err := loadData()
 	if err != nil {
-		log.Fatal("Failed to load data:", err)
+		fatal("Failed to load data", err)
 	}
 
 	// Parse templates with custom functions
@@ -45,71 +130,264 @@ func main() {
 	// Parse main templates and partials
 	templates, err := tmpl.ParseGlob("templates/*.html")
 	if err != nil {
-		log.Fatal("Failed to parse templates:", err)
+		fatal("Failed to parse templates", err)
 	}
 	templates, err = templates.ParseGlob("templates/partials/*.html")
 	if err != nil {
-		log.Fatal("Failed to parse template partials:", err)
+		fatal("Failed to parse template partials", err)
+	}
+
+	// Register game modes (the first one is the default for new lobbies)
+	gameModes := modes.NewRegistry(
+		spyfall.New(locations, challenges),
+		undercover.New(wordPairs),
+	)
+
+	// Redis is only dialed when a backend needs it
+	var redisClient *redis.Client
+	if storeKind == "redis" || brokerKind == "redis" {
+		redisClient, err = newRedisClient()
+		if err != nil {
+			fatal("Failed to connect to Redis", err)
+		}
+	}
+
+	lobbyStore, err := newLobbyStore(redisClient)
+	if err != nil {
+		fatal("Failed to open lobby store", err)
+	}
+
+	broker, err := newBroker(redisClient)
+	if err != nil {
+		fatal("Failed to start SSE broker", err)
+	}
+	sse.SetBroker(broker)
+	sse.SetOverflowPolicy(sseOverflow)
+	if storeKind == "redis" && brokerKind != "redis" {
+		slog.Warn("STORE=redis without BROKER=redis, players on other instances will miss live updates")
+	}
+
+	profileStore, err := newProfileStore(redisClient)
+	if err != nil {
+		fatal("Failed to open profile store", err)
 	}
 
 	// Initialize handler context
 	ctx := &handlers.Context{
-		LobbyStore: store.NewLobbyStore(),
+		LobbyStore: lobbyStore,
+		Profiles:   profileStore,
 		Templates:  templates,
-		Locations:  locations,
-		Challenges: challenges,
+		Modes:      gameModes,
 		BaseURL:    baseURL,
+		AdminToken: adminToken,
+	}
+	sse.SetRenderer(sse.FormatHTML, sse.RendererFunc(ctx.RenderHTML))
+
+	if storeKind != "redis" {
+		// Bots of restored games lost their pending timers
+		// (with a shared store every instance would resume them, so bots stay with the instance that started them)
+		ctx.ResumeBots()
+	}
+
+	// Expire abandoned lobbies in the background
+	// (with Redis every instance keeps the lobbies of its clients alive and one instance at a time sweeps)
+	var janitor *store.Janitor
+	if idleTTL > 0 {
+		janitor = store.NewJanitor(lobbyStore, idleTTL, sse.ClientCount, ctx.ExpireLobby)
+		janitor.Start()
 	}
 
-	// Routes
-	http.HandleFunc("/", ctx.HandleIndex)
-	http.HandleFunc("/create", ctx.HandleCreateLobby)
-	http.HandleFunc("/join", ctx.HandleJoinLobby)
-	http.HandleFunc("/join/", ctx.HandleJoinMux) // Multiplexer for GET (join screen) and POST (join action)
-	http.HandleFunc("/lobby/", ctx.HandleLobby)
+	// Routes (timed per route for /metrics)
+	route := func(pattern string, handler http.HandlerFunc) {
+		http.Handle(pattern, metrics.Instrument(pattern, handler))
+	}
+	route("/", ctx.HandleIndex)
+	route("/create", ctx.HandleCreateLobby)
+	route("/join", ctx.HandleJoinLobby)
+	route("/join/", ctx.HandleJoinMux) // Multiplexer for GET (join screen) and POST (join action)
+	route("/lobby/", ctx.HandleLobby)
+	// Live streams stay open for the whole visit, so they are left out of the latency histograms
 	http.HandleFunc("/sse/", ctx.HandleSSE)
-	http.HandleFunc("/start-game/", ctx.HandleStartGame)
+	http.HandleFunc("/ws/", ctx.HandleWebSocket)
+	// JSON API for non-browser clients
+	route(api.Version+"/", ctx.HandleAPI)
+	route("/lobby-settings/", ctx.HandleLobbySettings)
+	route("/add-bot/", ctx.HandleAddBot)
+	route("/remove-bot/", ctx.HandleRemoveBot)
+	route("/start-game/", ctx.HandleStartGame)
 	// Game multiplexer: phases (GET), actions (POST), and redirect helper
-	http.HandleFunc("/game/", ctx.HandleGameMux)
+	route("/game/", ctx.HandleGameMux)
 	// Results
-	http.HandleFunc("/results/", ctx.HandleResults)
+	route("/results/", ctx.HandleResults)
+	route("/history/", ctx.HandleHistory)
+	route("/export/", ctx.HandleExport)
+	// Profiles
+	route("/profile", ctx.HandleProfileMux)
+	route("/profile/", ctx.HandleProfileMux)
 	// Lobby/game lifecycle
-	http.HandleFunc("/restart-game/", ctx.HandleRestartGame)
-	http.HandleFunc("/close-lobby/", ctx.HandleCloseLobby)
-	http.HandleFunc("/leave-lobby/", ctx.HandleLeaveLobby)
-	http.HandleFunc("/select-host/", ctx.HandleSelectHost)
-	http.HandleFunc("/leave-lobby-with-host/", ctx.HandleLeaveLobbyWithHost)
+	route("/restart-game/", ctx.HandleRestartGame)
+	route("/close-lobby/", ctx.HandleCloseLobby)
+	route("/leave-lobby/", ctx.HandleLeaveLobby)
+	route("/select-host/", ctx.HandleSelectHost)
+	route("/leave-lobby-with-host/", ctx.HandleLeaveLobbyWithHost)
+	// Operator dashboard (needs ADMIN_TOKEN)
+	route("/admin", ctx.HandleAdmin)
+	route("/admin/", ctx.HandleAdmin)
+
+	// Prometheus metrics
+	metrics.RegisterLobbies(lobbyStore)
+	http.Handle("/metrics", metrics.Handler())
 
 	// Static files
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
 	port := ":8080"
-	log.Printf("Server starting on %s", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	// Every request gets an ID for its log lines
+	server := &http.Server{Addr: port, Handler: logging.Middleware(http.DefaultServeMux)}
+	// Shutdown closes the listener first, then open SSE streams send their notice and end
+	server.RegisterOnShutdown(sse.CloseStreams)
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Info("Server starting", "addr", port)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			fatal("Server failed", err)
+		}
+	}()
+
+	<-sigCtx.Done()
+	stop() // a second signal kills the process immediately
+	slog.Info("Shutting down, waiting for open requests", "timeout", shutdownTimeout)
+
+	ctx.BeginShutdown()
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		slog.Warn("Drain incomplete, closing remaining connections", "err", err)
+		server.Close()
+	}
+	// Open WebSocket connections were told to close when Shutdown began; let their handlers finish
+	if !ctx.Wait(shutdownTimeout) {
+		slog.Warn("WebSocket handlers still running, closing the stores anyway")
+	}
+
+	if janitor != nil {
+		janitor.Stop()
+	}
+	// Closing the stores writes their final snapshots
+	if err := lobbyStore.Close(); err != nil {
+		slog.Error("Failed to close lobby store", "err", err)
+	}
+	if err := profileStore.Close(); err != nil {
+		slog.Error("Failed to close profile store", "err", err)
+	}
+	if err := broker.Close(); err != nil {
+		slog.Error("Failed to close SSE broker", "err", err)
+	}
+	if redisClient != nil {
+		redisClient.Close()
+	}
+	slog.Info("Server stopped")
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
 
-// loadData loads locations and challenges from JSON files
-func loadData() ([]models.Location, []string, error) {
+// newLobbyStore creates the lobby store selected by the STORE env var
+func newLobbyStore(redisClient *redis.Client) (store.LobbyStore, error) {
+	switch storeKind {
+	case "", "memory":
+		slog.Info("Using in-memory lobby store")
+		return store.NewMemoryStore(), nil
+	case "file":
+		slog.Info("Using file lobby store", "path", storeFile)
+		return store.NewFileStore(storeFile, store.DefaultSnapshotInterval)
+	case "redis":
+		slog.Info("Using Redis lobby store")
+		return store.NewRedisStore(redisClient, "sus:lobby:", idleTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown STORE %q (want memory, file or redis)", storeKind)
+	}
+}
+
+// newProfileStore creates the profile store matching the STORE env var
+func newProfileStore(redisClient *redis.Client) (store.ProfileStore, error) {
+	switch storeKind {
+	case "file":
+		slog.Info("Using file profile store", "path", profileFile)
+		return store.NewFileProfileStore(profileFile, store.DefaultSnapshotInterval)
+	case "redis":
+		return store.NewRedisProfileStore(redisClient, "sus:profile:"), nil
+	default:
+		return store.NewMemoryProfileStore(), nil
+	}
+}
+
+// newBroker creates the SSE broker selected by the BROKER env var
+func newBroker(redisClient *redis.Client) (sse.Broker, error) {
+	switch brokerKind {
+	case "", "memory":
+		return sse.NewMemoryBroker(), nil
+	case "redis":
+		slog.Info("Using Redis SSE broker")
+		return sse.NewRedisBroker(redisClient, "sus:room:")
+	default:
+		return nil, fmt.Errorf("unknown BROKER %q (want memory or redis)", brokerKind)
+	}
+}
+
+// newRedisClient connects to REDIS_URL and checks that the server is reachable
+func newRedisClient() (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("pinging %s: %w", opts.Addr, err)
+	}
+	slog.Info("Connected to Redis", "addr", opts.Addr)
+	return client, nil
+}
+
+// loadData loads locations, challenges and word pairs from JSON files
+func loadData() ([]models.Location, []string, []models.WordPair, error) {
 	// Load locations
 	var locations []models.Location
 	locationData, err := os.ReadFile("data/places.json")
 	if err != nil {
-		return nil, nil, fmt.Errorf("reading places.json: %w", err)
+		return nil, nil, nil, fmt.Errorf("reading places.json: %w", err)
 	}
 	if err := json.Unmarshal(locationData, &locations); err != nil {
-		return nil, nil, fmt.Errorf("parsing places.json: %w", err)
+		return nil, nil, nil, fmt.Errorf("parsing places.json: %w", err)
 	}
 
 	// Load challenges
 	var challenges []string
 	challengeData, err := os.ReadFile("data/challenges.json")
 	if err != nil {
-		return nil, nil, fmt.Errorf("reading challenges.json: %w", err)
+		return nil, nil, nil, fmt.Errorf("reading challenges.json: %w", err)
 	}
 	if err := json.Unmarshal(challengeData, &challenges); err != nil {
-		return nil, nil, fmt.Errorf("parsing challenges.json: %w", err)
+		return nil, nil, nil, fmt.Errorf("parsing challenges.json: %w", err)
+	}
+
+	// Load word pairs (Undercover mode)
+	var wordPairs []models.WordPair
+	wordPairData, err := os.ReadFile("data/word_pairs.json")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading word_pairs.json: %w", err)
+	}
+	if err := json.Unmarshal(wordPairData, &wordPairs); err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing word_pairs.json: %w", err)
 	}
 
-	log.Printf("Loaded %d locations and %d challenges", len(locations), len(challenges))
-	return locations, challenges, nil
+	slog.Info("Loaded data", "locations", len(locations), "challenges", len(challenges), "word_pairs", len(wordPairs))
+	return locations, challenges, wordPairs, nil
 }